@@ -10,9 +10,15 @@ import (
 	"unicode"
 )
 
-func SimulatedAnnealing(metricFn m.Metric, initTemp float64, cf *kb.CharFreq, lowerIsBetter bool, lockSymbols bool) *kb.Keyboard {
+// The annealed keyboards start from template, usually qwerty with the layers, thumb keys and magic rules to use.
+// Thumb keys are only swapped when lockThumbs is false.
+func SimulatedAnnealing(metric m.Descriptor, initTemp float64, cf *kb.CharFreq, lockSymbols bool, lockThumbs bool, template *kb.Keyboard) *kb.Keyboard {
 	temp := initTemp
 	coolRate := 0.9995
+	metricFn := metric.Metric
+	lowerIsBetter := metric.LowerIsBetter
+	// differences in scores are compared to the temperature in n-grams
+	scale := metric.NgramsPerPoint(cf)
 
 	var isBetter func(int, int) bool
 	if lowerIsBetter {
@@ -22,16 +28,40 @@ func SimulatedAnnealing(metricFn m.Metric, initTemp float64, cf *kb.CharFreq, lo
 	}
 
 	// bestKb := kb.NewKeyboard("hdlvzcpui.ntrsxwfeoa;mkqj/gby',")
-	bestKb := kb.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	bestKb := template
 	bestScore := metricFn(bestKb, cf)
 	lockedIndexes := []int{}
 
+	thumbStart := 31
+	for _, layer := range bestKb.Layers {
+		if !layer.Shifted {
+			thumbStart += 31
+		}
+	}
+	if lockThumbs {
+		for i := range []rune(bestKb.Thumbs) {
+			lockedIndexes = append(lockedIndexes, thumbStart+i)
+		}
+	}
+
 	if lockSymbols {
-		for i, r := range bestKb.Layout {
+		for i, r := range []rune(bestKb.Layout) {
 			if !unicode.IsLetter(r) {
 				lockedIndexes = append(lockedIndexes, i)
 			}
 		}
+
+		// symbols on layers are locked as well
+		offset := 31
+		for _, layer := range bestKb.Layers {
+			if layer.Shifted {
+				continue
+			}
+			for i := 0; i < 31; i++ {
+				lockedIndexes = append(lockedIndexes, offset+i)
+			}
+			offset += 31
+		}
 	}
 
 	progress := ""
@@ -40,6 +70,9 @@ func SimulatedAnnealing(metricFn m.Metric, initTemp float64, cf *kb.CharFreq, lo
 	for temp > 1.0 {
 		swaps := int(math.Max(rand.Float64()*3+1, temp/500))
 		curKb := kb.MutateKeyboard(bestKb, swaps, lockedIndexes)
+		if _, ok := curKb.GroupId[kb.MagicKey]; ok && rand.Float64() < 0.5 {
+			curKb = kb.MutateMagic(curKb, 1)
+		}
 		score := metricFn(curKb, cf)
 
 		if isBetter(score, bestScore) {
@@ -53,7 +86,7 @@ func SimulatedAnnealing(metricFn m.Metric, initTemp float64, cf *kb.CharFreq, lo
 				delta = bestScore - score
 			}
 
-			if rand.Float64() < math.Exp(float64(-delta)/temp) {
+			if rand.Float64() < math.Exp(-float64(delta)*scale/temp) {
 				bestScore = score
 				bestKb = curKb
 			}
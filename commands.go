@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"kbannealing/corpus"
+	"kbannealing/formats"
+	kbd "kbannealing/keyboard"
+	m "kbannealing/metrics"
+	"kbannealing/render"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type command struct {
+	name string
+	// arguments after the flags, shown in the usage
+	args string
+	help string
+	run  func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"compare", "", "Print the stats of the layouts in a layouts file and the 000 optimized layouts", runCompare},
+	{"optimize", "", "Anneal layouts for each objective and save them to a layouts file as 000 optimized layouts", runOptimize},
+	{"analyze", "<layout>", "Print the top n-grams of each metric and the finger load of a layout", runAnalyze},
+	{"corpus", "", "Summarize frequency data, or export it into a folder for -folder", runCorpus},
+	{"render", "[layout...]", "Write an image of layouts, the 000 optimized layouts by default", runRender},
+	{"convert", "<layout|name|file>", "Convert a layout between row and column form and the files of other layout tools", runConvert},
+	{"robustness", "", "Compare the stats of the layouts on several corpora, scoring how much they depend on the corpus", runRobustness},
+	{"validate", "", "Check the layouts of a layouts file for wrong lengths, duplicate keys, missing letters and unknown geometries", runValidate},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	fmt.Println("Usage: kbannealing <command> [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.help)
+	}
+	fmt.Println()
+	fmt.Println("Without a command, compare is run. Use kbannealing <command> -h for the flags of a command.")
+}
+
+// Parses the flags of a command and runs it
+func (c command) execute(args []string) error {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kbannealing %s [flags] %s\n\n%s\n\nFlags:\n", c.name, c.args, c.help)
+		fs.PrintDefaults()
+	}
+
+	return c.run(fs, args)
+}
+
+func runCompare(fs *flag.FlagSet, args []string) error {
+	corpusOpts := addCorpusFlags(fs)
+	keyboardOpts := addKeyboardFlags(fs)
+	loadTargetFlag := fs.String("loadtarget", "", "Comma separated target usage percentage per finger, from left pinky to right pinky")
+	simulateFlag := fs.String("simulate", "", "Simulate typing a text file key by key, reporting finger travel")
+	chartFlag := fs.String("chart", "", "Write an SVG bar chart of the stats of all layouts, ex. stats.svg")
+	radarFlag := fs.Bool("radar", false, "Draw the -chart as a radar chart instead of a bar chart")
+	statsFlag := fs.String("stats", "stats.json", "JSON file the stats are written to, none if empty")
+	timestampFlag := fs.Bool("timestamp", false, "Add the current time to the name of the -stats file, keeping earlier stats")
+	bootstrapFlag := fs.Int("bootstrap", 0, "Resample the frequency data this many times for confidence intervals of the stats, ex. 200")
+	confidenceFlag := fs.Float64("confidence", 0.95, "Confidence level of the -bootstrap intervals")
+	bootstrapSizeFlag := fs.Int("bootstrapsize", 0, "Number of characters of the text the frequency data was counted from, for -bootstrap of -blend or scaled -folder data")
+	fs.Parse(args)
+
+	if *bootstrapFlag < 0 || *bootstrapSizeFlag < 0 || *confidenceFlag <= 0 || *confidenceFlag >= 1 {
+		return fmt.Errorf("-bootstrap and -bootstrapsize must not be negative and -confidence must be between 0 and 1")
+	}
+	// blends are scaled and folders may be, so their counts don't tell the size of the text
+	if *bootstrapFlag > 0 && *bootstrapSizeFlag == 0 {
+		switch corpusOpts.source() {
+		case "blend":
+			return fmt.Errorf("-bootstrap of -blend data needs the -bootstrapsize of the blended texts, as blends are scaled")
+		case "folder":
+			fmt.Printf("warning: -bootstrap takes the counts of %s as counted from a text, give -bootstrapsize if they are scaled\n", *corpusOpts.folder)
+		}
+	}
+
+	cf, err := corpusOpts.load()
+	if err != nil {
+		return err
+	}
+
+	loadTarget := m.DefaultLoadTarget
+	if *loadTargetFlag != "" {
+		loadTarget, err = m.ParseLoadTarget(*loadTargetFlag)
+		if err != nil {
+			return fmt.Errorf("invalid load target: %w", err)
+		}
+	}
+
+	var simText *m.Text
+	if *simulateFlag != "" {
+		simText, err = m.LoadText(*simulateFlag)
+		if err != nil {
+			return fmt.Errorf("could not load simulation text due to error: %w", err)
+		}
+	}
+
+	template, err := keyboardOpts.build()
+	if err != nil {
+		return err
+	}
+	keyboards, err := template.loadKeyboards(*keyboardOpts.layouts)
+	if err != nil {
+		return err
+	}
+
+	order := SortedKeys(keyboards)
+	statMap := ProcessStats(keyboards, cf, order)
+	if *statsFlag != "" {
+		path := *statsFlag
+		if *timestampFlag {
+			path = timestamped(path, time.Now())
+		}
+		if err := saveStatsToJSON(path, statMap); err != nil {
+			return fmt.Errorf("could not save stats due to error: %w", err)
+		}
+	}
+
+	fmt.Println(strings.Repeat("-", 65))
+	ProcessLoad(keyboards, cf, order, loadTarget)
+
+	if simText != nil {
+		fmt.Println(strings.Repeat("-", 65))
+		ProcessSimulation(keyboards, simText, order)
+	}
+
+	if *bootstrapFlag > 0 {
+		fmt.Println(strings.Repeat("-", 65))
+		ProcessBootstrap(keyboards, cf, order, m.BootstrapOptions{Samples: *bootstrapFlag, Confidence: *confidenceFlag, Seed: 1, Size: *bootstrapSizeFlag})
+	}
+
+	fmt.Println(strings.Repeat("-", 65))
+
+	for _, name := range order {
+		if strings.HasPrefix(name, "000") {
+			fmt.Println(name)
+			keyboards[name].PrintKeyboard()
+			fmt.Println(strings.Repeat("-", 65))
+		}
+	}
+
+	if *chartFlag != "" {
+		if err := render.SaveChart(*chartFlag, statMap, *radarFlag); err != nil {
+			return fmt.Errorf("could not chart stats due to error: %w", err)
+		}
+		fmt.Printf("Charted stats to %s\n", *chartFlag)
+	}
+
+	return nil
+}
+
+func runOptimize(fs *flag.FlagSet, args []string) error {
+	corpusOpts := addCorpusFlags(fs)
+	keyboardOpts := addKeyboardFlags(fs)
+	lockSymbolsFlag := fs.Bool("symbollock", false, "Lock symbols in the keyboard to qwerty's layout")
+	simulateFlag := fs.String("simulate", "", "Register the travel objective, the finger travel typing a text file, and optimize it by default")
+	objectivesFlag := fs.String("objectives", strings.Join(m.DefaultObjectives, ","), "Comma separated metrics to optimize, ex. sfb,roll")
+	scoringFlag := fs.String("scoring", "", "JSON file of weighted metrics to optimize, ex. {\"balanced\": {\"sfb\": -2, \"roll\": 1}}")
+	outFlag := fs.String("out", "", "JSON file the layouts are written to with the optimized layouts, the -layouts file if empty")
+	timestampFlag := fs.Bool("timestamp", false, "Add the current time to the name of the -out file, keeping the layouts file as it is")
+	authorFlag := fs.String("author", "", "Author recorded with the optimized layouts")
+	appendFlag := fs.Bool("append", false, "Add the optimized layouts under unique names instead of replacing earlier 000 optimized layouts")
+	fs.Parse(args)
+
+	chosen := false
+	fs.Visit(func(f *flag.Flag) {
+		chosen = chosen || f.Name == "objectives"
+	})
+
+	cf, err := corpusOpts.load()
+	if err != nil {
+		return err
+	}
+
+	var simText *m.Text
+	if *simulateFlag != "" {
+		simText, err = m.LoadText(*simulateFlag)
+		if err != nil {
+			return fmt.Errorf("could not load simulation text due to error: %w", err)
+		}
+		m.Register(m.Descriptor{
+			Name:          "travel",
+			Description:   "Finger travel typing " + *simulateFlag + ", in key widths",
+			Metric:        m.TravelMetric(simText),
+			LowerIsBetter: true,
+			Normalization: m.Hundredths,
+			LockColumns:   true,
+			RowDependent:  true,
+		})
+	}
+
+	// the default objectives are replaced by the objectives of a scoring file
+	objectives := []m.Descriptor{}
+	if chosen || *scoringFlag == "" {
+		names := *objectivesFlag
+		if !chosen && simText != nil {
+			names += ",travel"
+		}
+		objectives, err = m.ParseMetrics(names)
+		if err != nil {
+			return err
+		}
+	}
+	if *scoringFlag != "" {
+		scoring, err := m.LoadScoring(*scoringFlag)
+		if err != nil {
+			return fmt.Errorf("could not load scoring file due to error: %w", err)
+		}
+		objectives = append(objectives, scoring...)
+	}
+
+	// each objective is saved under its name
+	seen := map[string]bool{}
+	for _, o := range objectives {
+		if seen[o.Name] {
+			return fmt.Errorf("objective %q is given more than once", o.Name)
+		}
+		seen[o.Name] = true
+		if !o.Available(cf) {
+			return fmt.Errorf("objective %q needs %d-grams, which the frequency data lacks", o.Name, o.Order)
+		}
+	}
+
+	template, err := keyboardOpts.build()
+	if err != nil {
+		return err
+	}
+	layouts, err := loadLayoutFromJSON(*keyboardOpts.layouts)
+	if err != nil {
+		return fmt.Errorf("could not load layout JSON due to error: %w", err)
+	}
+
+	lockSymbols := *lockSymbolsFlag
+	lockThumbs := !*keyboardOpts.thumbLetter
+	start := template.template
+
+	// keys that can't move, recorded with the layouts
+	locked := ""
+	for _, r := range start.Layout {
+		if lockSymbols && !unicode.IsLetter(r) {
+			locked += string(r)
+		}
+	}
+	for _, r := range start.Thumbs {
+		if lockThumbs && r != kbd.Blank {
+			locked += string(r)
+		}
+	}
+	created := time.Now().UTC().Format(time.RFC3339)
+
+	annealedKeyboards := make([]*kbd.Keyboard, len(objectives))
+	fmt.Println("Finding optimal keyboards...")
+	startTemp := 1000000.0
+
+	for i, o := range objectives {
+		direction := "maximum"
+		if o.LowerIsBetter {
+			direction = "minimum"
+		}
+		fmt.Printf("Optimizing for %s %s...\n", direction, o.Name)
+		annealedKeyboards[i] = SimulatedAnnealing(o, startTemp, cf, lockSymbols, lockThumbs, start)
+		if !o.RowDependent {
+			annealedKeyboards[i] = kbd.OptimizeHomerow(annealedKeyboards[i], cf, lockSymbols, o.LockColumns)
+		}
+	}
+
+	fmt.Println(strings.Repeat("-", 65))
+	for i, o := range objectives {
+		kb := annealedKeyboards[i]
+		name := "000 optimized " + o.Name
+		if *appendFlag {
+			name = uniqueName(layouts, name)
+		}
+		fmt.Println(name)
+		kb.PrintKeyboard()
+		fmt.Println(strings.Repeat("-", 65))
+
+		scores := m.Stats(kb, cf)
+		scores[o.Name] = o.Normalize(o.Metric(kb, cf), cf)
+		// symbols may have been swapped between the layout and the symbol layers
+		layers := map[string]string{}
+		for _, layer := range kb.Layers {
+			if !layer.Shifted {
+				layers[layer.Name] = layer.Keys
+			}
+		}
+		layouts[name] = LayoutEntry{
+			Layout:      kb.Layout,
+			Thumbs:      kb.Thumbs,
+			Layers:      layers,
+			Magic:       kb.Magic.String(),
+			Author:      *authorFlag,
+			Description: fmt.Sprintf("Optimized for %s: %s", o.Name, o.Description),
+			Geometry:    "rowstag",
+			FingerMap:   "standard",
+			Locked:      locked,
+			Created:     created,
+			Corpus:      corpusOpts.describe(),
+			Scores:      scores,
+		}
+	}
+
+	out := *outFlag
+	if out == "" {
+		out = *keyboardOpts.layouts
+	}
+	if *timestampFlag {
+		out = timestamped(out, time.Now())
+	}
+	if err := saveLayoutToJSON(out, layouts); err != nil {
+		return fmt.Errorf("could not save layouts due to error: %w", err)
+	}
+	fmt.Printf("Saved layouts to %s\n", out)
+	return nil
+}
+
+func runAnalyze(fs *flag.FlagSet, args []string) error {
+	corpusOpts := addCorpusFlags(fs)
+	keyboardOpts := addKeyboardFlags(fs)
+	topFlag := fs.Int("top", 10, "Number of n-grams listed per metric")
+	jsonFlag := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("analyze needs the name of one layout")
+	}
+	name := fs.Arg(0)
+
+	cf, err := corpusOpts.load()
+	if err != nil {
+		return err
+	}
+	template, err := keyboardOpts.build()
+	if err != nil {
+		return err
+	}
+	keyboards, err := template.loadKeyboards(*keyboardOpts.layouts)
+	if err != nil {
+		return err
+	}
+
+	kb, ok := keyboards[name]
+	if !ok {
+		return fmt.Errorf("cannot analyze unknown layout %s", name)
+	}
+
+	report := m.Analyze(name, kb, cf, *topFlag)
+	if *jsonFlag {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not encode analysis due to error: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	return report.WriteText(os.Stdout)
+}
+
+func runCorpus(fs *flag.FlagSet, args []string) error {
+	corpusOpts := addCorpusFlags(fs)
+	outFlag := fs.String("out", "", "Write the frequency data into a folder of monograms, bigrams, trigrams and quadgrams.txt")
+	topFlag := fs.Int("top", 10, "Number of most frequent n-grams listed in the summary")
+	fs.Parse(args)
+
+	cf, err := corpusOpts.load()
+	if err != nil {
+		return err
+	}
+
+	if *outFlag != "" {
+		if err := cf.SaveToFolder(*outFlag); err != nil {
+			return fmt.Errorf("could not export frequency data due to error: %w", err)
+		}
+		fmt.Printf("Exported frequency data to %s\n", *outFlag)
+		return nil
+	}
+
+	chars := map[string]int{}
+	for c, val := range cf.Chars {
+		chars[string(c)] = val
+	}
+
+	fmt.Printf("%-12s %-10s %-12s %s\n", "N-grams", "Distinct", "Total", "Most frequent")
+	fmt.Println(strings.Repeat("-", 65))
+	for _, table := range []struct {
+		name   string
+		ngrams map[string]int
+	}{{"monograms", chars}, {"bigrams", cf.Bigrams}, {"trigrams", cf.Trigrams}, {"quadgrams", cf.Quadgrams}} {
+		total := 0
+		keys := make([]string, 0, len(table.ngrams))
+		for ngram, val := range table.ngrams {
+			total += val
+			keys = append(keys, ngram)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			return cmp.Or(cmp.Compare(table.ngrams[b], table.ngrams[a]), cmp.Compare(a, b))
+		})
+
+		top := make([]string, 0, *topFlag)
+		for _, ngram := range keys[:min(*topFlag, len(keys))] {
+			top = append(top, fmt.Sprintf("%q", ngram))
+		}
+		fmt.Printf("%-12s %-10d %-12d %s\n", table.name, len(table.ngrams), total, strings.Join(top, " "))
+	}
+
+	return nil
+}
+
+func runRender(fs *flag.FlagSet, args []string) error {
+	corpusOpts := addCorpusFlags(fs)
+	keyboardOpts := addKeyboardFlags(fs)
+	outFlag := fs.String("out", "combined.png", "Image file written, as SVG if it ends in .svg and PNG otherwise")
+	heatmapFlag := fs.Bool("heatmap", false, "Color keys by the frequency of their character")
+	fingersFlag := fs.Bool("fingers", false, "Color keys by the finger pressing them")
+	fs.Parse(args)
+
+	template, err := keyboardOpts.build()
+	if err != nil {
+		return err
+	}
+	keyboards, err := template.loadKeyboards(*keyboardOpts.layouts)
+	if err != nil {
+		return err
+	}
+
+	names := fs.Args()
+	if len(names) == 0 {
+		for _, name := range SortedKeys(keyboards) {
+			if strings.HasPrefix(name, "000") {
+				names = append(names, name)
+			}
+		}
+	}
+
+	images := []render.Layout{}
+	for _, name := range names {
+		kb, ok := keyboards[name]
+		if !ok {
+			return fmt.Errorf("cannot render unknown layout %s", name)
+		}
+		images = append(images, render.Layout{Name: strings.TrimPrefix(name, "000 "), Keyboard: kb})
+	}
+
+	opts := render.Options{Fingers: *fingersFlag}
+	if *heatmapFlag {
+		// frequency data is only loaded for heatmaps
+		opts.Heatmap, err = corpusOpts.load()
+		if err != nil {
+			return err
+		}
+	}
+	if err := render.SaveFile(*outFlag, images, opts); err != nil {
+		return fmt.Errorf("could not render layouts due to error: %w", err)
+	}
+	fmt.Printf("Rendered %d layouts to %s\n", len(images), *outFlag)
+	return nil
+}
+
+func runConvert(fs *flag.FlagSet, args []string) error {
+	fromFlag := fs.String("from", "row", "Form of the layout given: row or col, name for a layout of the -layouts file, "+
+		"or a file format: "+formatNames())
+	toFlag := fs.String("to", "col", "Form to convert the layout to: row, col, keyboard to print it, layouts to add it to "+
+		"the -layouts file, or a file format")
+	keyboardOpts := addKeyboardFlags(fs)
+	layoutsFlag := keyboardOpts.layouts
+	nameFlag := fs.String("name", "", "Name of the converted layout, by default its name in the file or the layouts file")
+	outFlag := fs.String("out", "", "File a layout in a file format is written to, standard output if empty")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("convert needs one layout, layout name or file")
+	}
+	arg := fs.Arg(0)
+
+	var l formats.Layout
+	// thumb keys, layer keys and magic rules of a layout of the layouts file
+	var entry LayoutEntry
+	switch *fromFlag {
+	case "row", "col":
+		if n := utf8.RuneCountInString(arg); *fromFlag == "col" && n == 31 {
+			arg = kbd.ColLayoutToRow(arg)
+		}
+		var err error
+		l.Keyboard, err = kbd.ParseKeyboard(arg)
+		if err != nil {
+			return err
+		}
+	case "name":
+		layouts, err := loadLayoutFromJSON(*layoutsFlag)
+		if err != nil {
+			return fmt.Errorf("could not load layout JSON due to error: %w", err)
+		}
+		var ok bool
+		entry, ok = layouts[arg]
+		if !ok {
+			return fmt.Errorf("no layout %s in %s", arg, *layoutsFlag)
+		}
+		if err := kbd.Validate(arg, entry.Layout, entry.Thumbs, entry.Layers, entry.Geometry, entry.FingerMap, ""); err != nil {
+			return err
+		}
+		l = formats.Layout{Name: arg, Keyboard: kbd.NewKeyboard(entry.Layout)}
+	default:
+		format, err := formats.Lookup(*fromFlag)
+		if err != nil {
+			return fmt.Errorf("invalid layout form %s, use row, col, name or a file format (%s)", *fromFlag, formatNames())
+		}
+		if format.Import == nil {
+			return fmt.Errorf("%s files can only be written", format.Name)
+		}
+		l, err = importLayout(format, arg)
+		if err != nil {
+			return fmt.Errorf("could not import %s: %w", arg, err)
+		}
+		if l.Name == "" {
+			l.Name = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+		}
+	}
+	l.Name = cmp.Or(*nameFlag, l.Name)
+
+	switch *toFlag {
+	case "row":
+		fmt.Println(l.Keyboard.Layout)
+	case "col":
+		fmt.Println(kbd.RowLayoutToCol(l.Keyboard.Layout))
+	case "keyboard":
+		l.Keyboard.PrintKeyboard()
+	case "layouts":
+		if l.Name == "" {
+			return fmt.Errorf("the layout needs a -name to be added to %s", *layoutsFlag)
+		}
+		layouts, err := loadLayoutFromJSON(*layoutsFlag)
+		if err != nil {
+			return fmt.Errorf("could not load layout JSON due to error: %w", err)
+		}
+		name := uniqueName(layouts, l.Name)
+		layouts[name] = LayoutEntry{
+			Layout:      l.Keyboard.Layout,
+			Thumbs:      entry.Thumbs,
+			Layers:      entry.Layers,
+			Magic:       entry.Magic,
+			Description: fmt.Sprintf("Converted from %s", *fromFlag),
+			Geometry:    "rowstag",
+			FingerMap:   "standard",
+			Created:     time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := saveLayoutToJSON(*layoutsFlag, layouts); err != nil {
+			return fmt.Errorf("could not save layouts due to error: %w", err)
+		}
+		fmt.Printf("Added %s to %s\n", name, *layoutsFlag)
+	default:
+		format, err := formats.Lookup(*toFlag)
+		if err != nil {
+			return fmt.Errorf("invalid layout form %s, use row, col, keyboard, layouts or a file format (%s)", *toFlag, formatNames())
+		}
+		// keymaps include the layers, thumb keys and adaptive keys of the keyboard flags
+		template, err := keyboardOpts.build()
+		if err != nil {
+			return err
+		}
+		entry.Layout = l.Keyboard.Layout
+		l.Keyboard, err = template.keyboard(entry)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := format.Export(&buf, l); err != nil {
+			return err
+		}
+		if *outFlag == "" {
+			_, err = os.Stdout.Write(buf.Bytes())
+			return err
+		}
+		if err := writeFileAtomic(*outFlag, buf.Bytes()); err != nil {
+			return fmt.Errorf("could not write %s due to error: %w", *outFlag, err)
+		}
+		fmt.Printf("Saved %s to %s\n", l.Name, *outFlag)
+	}
+	return nil
+}
+
+// Reads a layout in a file format from a file, or from standard input if path is -
+func importLayout(format formats.Format, path string) (formats.Layout, error) {
+	if path == "-" {
+		return format.Import(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return formats.Layout{}, err
+	}
+	defer f.Close()
+	return format.Import(f)
+}
+
+func formatNames() string {
+	names := make([]string, len(formats.Formats))
+	for i, f := range formats.Formats {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func runValidate(fs *flag.FlagSet, args []string) error {
+	corpusOpts := addCorpusFlags(fs)
+	layoutsFlag := fs.String("layouts", "layouts.json", "JSON file of layouts in row form by name")
+	alphabetFlag := fs.String("alphabet", kbd.DefaultAlphabet, "Letters every layout must have, none are checked if empty")
+	fs.Parse(args)
+
+	layouts, err := loadLayoutFromJSON(*layoutsFlag)
+	if err != nil {
+		return fmt.Errorf("could not load layout JSON due to error: %w", err)
+	}
+	cf, err := corpusOpts.load()
+	if err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, name := range SortedKeys(layouts) {
+		entry := layouts[name]
+		err := kbd.Validate(name, entry.Layout, entry.Thumbs, entry.Layers, entry.Geometry, entry.FingerMap, entry.offLayout(*alphabetFlag))
+		var layoutErr *kbd.LayoutError
+		if errors.As(err, &layoutErr) {
+			invalid++
+			fmt.Printf("%s:\n", name)
+			for _, problem := range layoutErr.Problems {
+				fmt.Printf("  %s\n", problem)
+			}
+			continue
+		}
+
+		// keys the frequency data never uses are only worth a warning
+		if err := kbd.NewKeyboard(entry.Layout).CheckCorpus(cf); err != nil {
+			fmt.Printf("%s:\n  warning: %s\n", name, err)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d layouts in %s are invalid", invalid, len(layouts), *layoutsFlag)
+	}
+	fmt.Printf("All %d layouts in %s are valid\n", len(layouts), *layoutsFlag)
+	return nil
+}
+
+// The corpora shipped in CharFreqData
+const defaultCorpora = "CharFreqData/mt-quotes,CharFreqData/MayznerNgramData,CharFreqData/google-10000-english-usa.txt," +
+	"CharFreqData/monkeytype-10k.txt,CharFreqData/monkeytype-quotes.txt"
+
+func runRobustness(fs *flag.FlagSet, args []string) error {
+	keyboardOpts := addKeyboardFlags(fs)
+	corporaFlag := fs.String("corpora", defaultCorpora, "Comma separated folders or word list txt files to compare the layouts on")
+	langFlag := fs.String("lang", "", "Normalize the word list corpora for a language (en, de, nordic, ru) before counting n-grams")
+	metricsFlag := fs.String("metrics", "", "Comma separated metrics to compare, by default the stats available on every corpus")
+	fs.Parse(args)
+
+	sources, err := corpus.ParseSources(*corporaFlag)
+	if err != nil {
+		return err
+	}
+	norm := corpus.Normalization{}
+	if *langFlag != "" {
+		norm, err = corpus.LanguageNormalization(*langFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, len(sources))
+	cfs := make([]*kbd.CharFreq, len(sources))
+	for i, source := range sources {
+		names[i] = strings.TrimSuffix(filepath.Base(source.Path), filepath.Ext(source.Path))
+		cfs[i], err = source.Load(norm)
+		if err != nil {
+			return fmt.Errorf("could not load frequency data %s due to error: %w", source.Path, err)
+		}
+	}
+
+	var stats []m.Descriptor
+	if *metricsFlag != "" {
+		stats, err = m.ParseMetrics(*metricsFlag)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, d := range statDescriptors(cfs[0]) {
+			if !slices.ContainsFunc(cfs, func(cf *kbd.CharFreq) bool { return !d.Available(cf) }) {
+				stats = append(stats, d)
+			}
+		}
+	}
+
+	template, err := keyboardOpts.build()
+	if err != nil {
+		return err
+	}
+	keyboards, err := template.loadKeyboards(*keyboardOpts.layouts)
+	if err != nil {
+		return err
+	}
+
+	ProcessRobustness(m.ScoreCorpora(keyboards, names, cfs, stats))
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertFromName(t *testing.T) {
+	dir := t.TempDir()
+	layoutsPath := filepath.Join(dir, "layouts.json")
+	layouts := LayoutMap{
+		"thumb e": {Layout: "qwr·tyuiopasdfghjkl;'zxcvbnm,./", Thumbs: "e "},
+	}
+	if err := saveLayoutToJSON(layoutsPath, layouts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the thumb keys of the layout are exported, so e is still typed
+	out := filepath.Join(dir, "keymap.c")
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	if err := runConvert(fs, []string{"-from", "name", "-to", "qmk", "-layouts", layoutsPath, "-out", out, "thumb e"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "KC_E,") {
+		t.Errorf("convert -from name -to qmk = %s but want the e on the thumb key", data)
+	}
+}
@@ -0,0 +1,141 @@
+package corpus
+
+import (
+	"fmt"
+	kbd "kbannealing/keyboard"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Total count of each n-gram table in a blended CharFreq
+const blendScale = 100_000_000
+
+// A corpus with its relative weight in a blend.
+// Path is either a folder of monograms, bigrams and trigrams.txt or a word list txt file.
+type Source struct {
+	Path   string
+	Weight float64
+}
+
+// Parses a comma separated list of sources with optional weights, ex. "CharFreqData/mt-quotes=2,code.txt"
+// Sources without a weight have a weight of 1.
+func ParseSources(s string) ([]Source, error) {
+	sources := []Source{}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		source := Source{part, 1}
+		if idx := strings.LastIndex(part, "="); idx != -1 {
+			weight, err := strconv.ParseFloat(part[idx+1:], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight for %q: %w", part[:idx], err)
+			}
+			if weight < 0 {
+				return nil, fmt.Errorf("weight for %q is negative", part[:idx])
+			}
+			source = Source{part[:idx], weight}
+		}
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no corpus sources given")
+	}
+	return sources, nil
+}
+
+// Loads the frequency data of a source, word lists are normalized with norm
+func (s Source) Load(norm Normalization) (*kbd.CharFreq, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return kbd.CharFreqFromFolder(s.Path)
+	}
+	return FromText(s.Path, norm)
+}
+
+// Loads and blends sources by their weights
+func LoadBlend(sources []Source, norm Normalization) (*kbd.CharFreq, error) {
+	cfs := make([]*kbd.CharFreq, len(sources))
+	weights := make([]float64, len(sources))
+
+	for i, source := range sources {
+		cf, err := source.Load(norm)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.Path, err)
+		}
+		cfs[i] = cf
+		weights[i] = source.Weight
+	}
+
+	return Blend(cfs, weights)
+}
+
+// Combines several CharFreqs into one. Each n-gram table of a source is normalized to its relative
+// frequencies first, so a source's share of the blend depends only on its weight, not on its size.
+func Blend(cfs []*kbd.CharFreq, weights []float64) (*kbd.CharFreq, error) {
+	if len(cfs) != len(weights) {
+		return nil, fmt.Errorf("got %d sources but %d weights", len(cfs), len(weights))
+	}
+
+	weightSum := 0.0
+	for _, weight := range weights {
+		weightSum += weight
+	}
+	if weightSum <= 0 {
+		return nil, fmt.Errorf("weights sum up to %f", weightSum)
+	}
+
+	chars := map[rune]float64{}
+	bigrams := map[string]float64{}
+	trigrams := map[string]float64{}
+	quadgrams := map[string]float64{}
+
+	for i, cf := range cfs {
+		share := weights[i] / weightSum
+		addShare(chars, cf.Chars, share)
+		addShare(bigrams, cf.Bigrams, share)
+		addShare(trigrams, cf.Trigrams, share)
+		addShare(quadgrams, cf.Quadgrams, share)
+	}
+
+	return &kbd.CharFreq{
+		Chars:     toCounts(chars),
+		Bigrams:   toCounts(bigrams),
+		Trigrams:  toCounts(trigrams),
+		Quadgrams: toCounts(quadgrams),
+	}, nil
+}
+
+func addShare[K comparable](dst map[K]float64, src map[K]int, share float64) {
+	total := 0
+	for _, val := range src {
+		total += val
+	}
+	if total == 0 {
+		return
+	}
+
+	for key, val := range src {
+		dst[key] += float64(val) / float64(total) * share
+	}
+}
+
+func toCounts[K comparable](freqs map[K]float64) map[K]int {
+	counts := make(map[K]int, len(freqs))
+	for key, freq := range freqs {
+		if count := int(math.Round(freq * blendScale)); count > 0 {
+			counts[key] = count
+		}
+	}
+	return counts
+}
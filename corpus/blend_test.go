@@ -0,0 +1,78 @@
+package corpus
+
+import (
+	kbd "kbannealing/keyboard"
+	"testing"
+)
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources("CharFreqData/mt-quotes=2, code.txt,C:/chat.txt=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Source{{"CharFreqData/mt-quotes", 2}, {"code.txt", 1}, {"C:/chat.txt", 0.5}}
+	if len(sources) != len(expected) {
+		t.Fatalf("ParseSources() = %v but want %v", sources, expected)
+	}
+	for i := range expected {
+		if sources[i] != expected[i] {
+			t.Errorf("ParseSources()[%d] = %v but want %v", i, sources[i], expected[i])
+		}
+	}
+
+	for _, s := range []string{"", "a=x", "a=-1"} {
+		if _, err := ParseSources(s); err == nil {
+			t.Errorf("ParseSources(%q) should fail", s)
+		}
+	}
+}
+
+func TestBlend(t *testing.T) {
+	a := &kbd.CharFreq{
+		Chars:    map[rune]int{'a': 1000},
+		Bigrams:  map[string]int{"ab": 10},
+		Trigrams: map[string]int{},
+	}
+	b := &kbd.CharFreq{
+		Chars:    map[rune]int{'a': 1, 'b': 1},
+		Bigrams:  map[string]int{"ba": 3},
+		Trigrams: map[string]int{"bab": 1},
+	}
+
+	cf, err := Blend([]*kbd.CharFreq{a, b}, []float64{3, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a is 100% of the first source and 50% of the second
+	if cf.Chars['a'] != blendScale*7/8 || cf.Chars['b'] != blendScale/8 {
+		t.Errorf("Blend() chars = %v", cf.Chars)
+	}
+	if cf.Bigrams["ab"] != blendScale*3/4 || cf.Bigrams["ba"] != blendScale/4 {
+		t.Errorf("Blend() bigrams = %v", cf.Bigrams)
+	}
+	if cf.Trigrams["bab"] != blendScale/4 {
+		t.Errorf("Blend() trigrams = %v", cf.Trigrams)
+	}
+
+	if _, err := Blend([]*kbd.CharFreq{a}, []float64{0}); err == nil {
+		t.Error("Blend() with zero weights should fail")
+	}
+}
+
+func TestLoadBlend(t *testing.T) {
+	sources := []Source{{"../CharFreqData/mt-quotes", 1}, {"../CharFreqData/monkeytype-10k.txt", 1}}
+
+	cf, err := LoadBlend(sources, Languages["en"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Chars['e'] == 0 || cf.Trigrams["the"] == 0 {
+		t.Error("LoadBlend() is missing common n-grams")
+	}
+
+	if _, err := LoadBlend([]Source{{"../CharFreqData/missing", 1}}, Normalization{}); err == nil {
+		t.Error("LoadBlend() of a missing source should fail")
+	}
+}
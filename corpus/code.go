@@ -0,0 +1,69 @@
+package corpus
+
+import (
+	"io/fs"
+	kbd "kbannealing/keyboard"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// File extensions scanned by FromCode by default
+var CodeExtensions = []string{
+	".c", ".cc", ".cpp", ".cs", ".go", ".h", ".hpp", ".java", ".js", ".jsx", ".kt", ".lua",
+	".php", ".py", ".rb", ".rs", ".scala", ".sh", ".sql", ".swift", ".ts", ".tsx", ".zig",
+}
+
+// Folders that hold dependencies or tooling rather than code that is typed
+var skippedDirs = []string{"node_modules", "vendor", "target", "build", "dist", "__pycache__"}
+
+// Keys of the US layout that code is typed with. Shifted symbols are typed on their base key,
+// ex. '(' is counted as '9' and '_' as '-', and capitals as their lowercase letter.
+var CodeNormalization = Normalization{
+	FoldCase: true,
+	Unshift:  UsShiftMap,
+	Keep:     "abcdefghijklmnopqrstuvwxyz0123456789`-=[]\\;',./",
+}
+
+// Scan options for source code: spaces between tokens are counted,
+// but indentation after a newline is not, as editors indent automatically.
+var CodeScanOptions = ScanOptions{
+	Normalization: CodeNormalization,
+	Space:         true,
+	LineBreaks:    true,
+}
+
+// Walks a directory of source code and counts the n-grams of the keys typed to write it,
+// scanned with opts, usually CodeScanOptions.
+// Only files with one of the given extensions are scanned, CodeExtensions is used if none are given.
+// Hidden folders and dependency folders such as vendor or node_modules are skipped.
+func FromCode(root string, extensions []string, opts ScanOptions) (*kbd.CharFreq, error) {
+	if len(extensions) == 0 {
+		extensions = CodeExtensions
+	}
+
+	paths := []string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || slices.Contains(skippedDirs, name)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if slices.Contains(extensions, filepath.Ext(path)) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return FromFiles(paths, opts)
+}
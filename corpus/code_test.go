@@ -0,0 +1,46 @@
+package corpus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromCode(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "pkg"), 0755)
+	os.MkdirAll(filepath.Join(dir, "vendor"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+
+	code := "func Add(a, b int) int {\n\treturn a_b\n}\n"
+	os.WriteFile(filepath.Join(dir, "pkg", "add.go"), []byte(code), 0644)
+	os.WriteFile(filepath.Join(dir, "vendor", "dep.go"), []byte("zzz"), 0644)
+	os.WriteFile(filepath.Join(dir, ".git", "config.go"), []byte("zzz"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("zzz"), 0644)
+
+	cf, err := FromCode(dir, nil, CodeScanOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cf.Chars['z'] != 0 {
+		t.Error("FromCode() scanned a skipped file")
+	}
+
+	// shifted symbols are counted on their base keys
+	expected := map[string]int{"add9": 1, "a, b": 1, "nt0 ": 1, "a-b": 1, "n a": 1}
+	for key, val := range expected {
+		runes := []rune(key)
+		for i := 0; i+3 <= len(runes); i++ {
+			trigram := string(runes[i : i+3])
+			if cf.Trigrams[trigram] < val {
+				t.Errorf("Trigrams[%q] = %d but want at least %d", trigram, cf.Trigrams[trigram], val)
+			}
+		}
+	}
+
+	// newlines end the sequence, so indentation is not typed
+	if cf.Trigrams["[ r"] != 0 || cf.Bigrams["[r"] != 0 || cf.Chars['\t'] != 0 {
+		t.Error("FromCode() counted n-grams across a newline")
+	}
+}
@@ -0,0 +1,239 @@
+package corpus
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	kbd "kbannealing/keyboard"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Accumulates n-gram counts into a CharFreq from a stream of characters.
+// Only the last few characters are kept, so memory is bounded by the number of distinct n-grams.
+type counter struct {
+	cf     *kbd.CharFreq
+	window []rune
+	size   int
+}
+
+// Quadgrams are only counted if quadgrams is true
+func newCounter(quadgrams bool) *counter {
+	size := 3
+	if quadgrams {
+		size = 4
+	}
+
+	return &counter{
+		cf: &kbd.CharFreq{
+			Chars:     make(map[rune]int),
+			Bigrams:   make(map[string]int),
+			Trigrams:  make(map[string]int),
+			Quadgrams: make(map[string]int),
+		},
+		window: make([]rune, 0, size),
+		size:   size,
+	}
+}
+
+// Counts a character along with the n-grams it ends
+func (c *counter) push(r rune) {
+	if len(c.window) == c.size {
+		copy(c.window, c.window[1:])
+		c.window = c.window[:c.size-1]
+	}
+	c.window = append(c.window, r)
+
+	c.cf.Chars[r]++
+	n := len(c.window)
+	if n >= 2 {
+		c.cf.Bigrams[string(c.window[n-2:])]++
+	}
+	if n >= 3 {
+		c.cf.Trigrams[string(c.window[n-3:])]++
+	}
+	if n >= 4 {
+		c.cf.Quadgrams[string(c.window[n-4:])]++
+	}
+}
+
+// Ends the current sequence, no n-gram is counted across a reset
+func (c *counter) reset() {
+	c.window = c.window[:0]
+}
+
+func merge(dst *kbd.CharFreq, src *kbd.CharFreq) {
+	for key, val := range src.Chars {
+		dst.Chars[key] += val
+	}
+	for key, val := range src.Bigrams {
+		dst.Bigrams[key] += val
+	}
+	for key, val := range src.Trigrams {
+		dst.Trigrams[key] += val
+	}
+	for key, val := range src.Quadgrams {
+		dst.Quadgrams[key] += val
+	}
+}
+
+// Options for scanning corpora
+type ScanOptions struct {
+	Normalization Normalization
+	// Count whitespace as a single space character, so n-grams span word boundaries.
+	// Otherwise whitespace splits the text like a dropped character.
+	Space bool
+	// Newlines end the sequence instead of counting as a space, like pressing enter in an editor
+	// that indents the next line automatically.
+	LineBreaks bool
+	// Count quadgrams as well
+	Quadgrams bool
+	// Number of files scanned at the same time, defaults to the number of CPUs
+	Workers int
+}
+
+// Streams n-grams from a reader of arbitrary size, lines are not limited in length
+func Scan(r io.Reader, opts ScanOptions) (*kbd.CharFreq, error) {
+	c := newCounter(opts.Quadgrams)
+	if err := c.scan(r, opts); err != nil {
+		return nil, err
+	}
+	return c.cf, nil
+}
+
+func (c *counter) scan(r io.Reader, opts ScanOptions) error {
+	reader := bufio.NewReaderSize(r, 1<<16)
+	lastSpace := true
+	lineBreak := false
+
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if unicode.IsSpace(r) {
+			if opts.LineBreaks && r == '\n' {
+				lineBreak = true
+			}
+			lastSpace = true
+			continue
+		}
+
+		if lastSpace {
+			if !opts.Space || lineBreak {
+				c.reset()
+			} else if len(c.window) > 0 {
+				c.push(' ')
+			}
+		}
+		lastSpace = false
+		lineBreak = false
+
+		normalized := opts.Normalization.NormalizeRune(r)
+		if normalized == "" {
+			c.reset()
+			continue
+		}
+		for _, n := range normalized {
+			c.push(n)
+		}
+	}
+
+	c.reset()
+	return nil
+}
+
+// Opens a corpus file, transparently decompressing .gz files
+func open(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &gzipFile{gz, file}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	return errors.Join(g.Reader.Close(), g.file.Close())
+}
+
+func scanFile(path string, opts ScanOptions) (*kbd.CharFreq, error) {
+	file, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Scan(file, opts)
+}
+
+// Scans several corpus files concurrently and combines their n-grams
+func FromFiles(paths []string, opts ScanOptions) (*kbd.CharFreq, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]*kbd.CharFreq, len(paths))
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = scanFile(paths[i], opts)
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	cf := newCounter(opts.Quadgrams).cf
+	for _, result := range results {
+		merge(cf, result)
+	}
+	return cf, nil
+}
+
+// Reads a word list txt file, normalizing the text before counting n-grams.
+// N-grams are not counted across words.
+func FromText(path string, norm Normalization) (*kbd.CharFreq, error) {
+	return FromFiles([]string{path}, ScanOptions{Normalization: norm})
+}
+
+// Reads prose files (optionally gzipped), counting n-grams across word boundaries including space
+func FromProse(paths []string, norm Normalization) (*kbd.CharFreq, error) {
+	return FromFiles(paths, ScanOptions{Normalization: norm, Space: true})
+}
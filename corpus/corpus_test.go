@@ -0,0 +1,98 @@
+package corpus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanProse(t *testing.T) {
+	cf, err := Scan(strings.NewReader("The cat,\n  the hat"), ScanOptions{Normalization: Languages["en"], Space: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"t, ": 1, "e c": 1, "he ": 2, ", t": 1, "at,": 1, "e h": 1}
+	for key, val := range expected {
+		if cf.Trigrams[key] != val {
+			t.Errorf("Trigrams[%q] = %d but want %d", key, cf.Trigrams[key], val)
+		}
+	}
+
+	if cf.Chars[' '] != 3 {
+		t.Errorf("Chars[' '] = %d but want 3", cf.Chars[' '])
+	}
+
+	cf, err = Scan(strings.NewReader("The cat"), ScanOptions{Normalization: Languages["en"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Chars[' '] != 0 || cf.Bigrams["ec"] != 0 || cf.Trigrams["the"] != 1 {
+		t.Errorf("Scan() without spaces counted n-grams across words")
+	}
+}
+
+func TestScanQuadgrams(t *testing.T) {
+	cf, err := Scan(strings.NewReader("the theme"), ScanOptions{Space: true, Quadgrams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"the ": 1, "he t": 1, "them": 1, "heme": 1}
+	for key, val := range expected {
+		if cf.Quadgrams[key] != val {
+			t.Errorf("Quadgrams[%q] = %d but want %d", key, cf.Quadgrams[key], val)
+		}
+	}
+	if cf.Trigrams["the"] != 2 {
+		t.Errorf("Trigrams[\"the\"] = %d but want 2", cf.Trigrams["the"])
+	}
+
+	cf, _ = Scan(strings.NewReader("the theme"), ScanOptions{Space: true})
+	if len(cf.Quadgrams) != 0 {
+		t.Error("Scan() counted quadgrams without Quadgrams")
+	}
+}
+
+func TestScanLongLine(t *testing.T) {
+	line := strings.Repeat("ab", 100000)
+
+	cf, err := Scan(strings.NewReader(line), ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cf.Bigrams["ab"] != 100000 || cf.Bigrams["ba"] != 99999 {
+		t.Errorf("Scan() = ab %d, ba %d but want 100000, 99999", cf.Bigrams["ab"], cf.Bigrams["ba"])
+	}
+}
+
+func TestFromFilesGzip(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("the quick fox"))
+	gz.Close()
+
+	gzPath := filepath.Join(dir, "a.txt.gz")
+	txtPath := filepath.Join(dir, "b.txt")
+	os.WriteFile(gzPath, buf.Bytes(), 0644)
+	os.WriteFile(txtPath, []byte("the lazy dog"), 0644)
+
+	cf, err := FromProse([]string{gzPath, txtPath}, Normalization{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cf.Trigrams["the"] != 2 || cf.Trigrams["k f"] != 1 || cf.Trigrams["y d"] != 1 {
+		t.Errorf("FromProse() did not combine both files: %v", cf.Trigrams)
+	}
+
+	if _, err := FromProse([]string{filepath.Join(dir, "missing.txt")}, Normalization{}); err == nil {
+		t.Error("FromProse() of a missing file should fail")
+	}
+}
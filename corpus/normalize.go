@@ -0,0 +1,142 @@
+package corpus
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Rules for turning raw text into the characters that are actually on the keyboard
+type Normalization struct {
+	// Lowercase every character, ex. "A" -> "a"
+	FoldCase bool
+	// Shifted characters are typed with their base key, ex. ':' -> ';'
+	Unshift map[rune]rune
+	// Characters that are not kept are decomposed into their base letters, ex. 'é' -> "e"
+	Decompose map[rune]string
+	// Characters allowed in the output. Any other character is dropped and splits the text,
+	// so no n-gram is counted across it. Empty keeps every character.
+	Keep string
+}
+
+// Shifted symbols of the US layout mapped to the key they are typed on
+var UsShiftMap = map[rune]rune{
+	'~': '`', '!': '1', '@': '2', '#': '3', '$': '4', '%': '5', '^': '6', '&': '7', '*': '8',
+	'(': '9', ')': '0', '_': '-', '+': '=', '{': '[', '}': ']', '|': '\\',
+	':': ';', '"': '\'', '<': ',', '>': '.', '?': '/',
+}
+
+// Accented latin letters decomposed into the letters they are built from
+var AccentMap = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ß': "ss", 'ť': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	'’': "'", '‘': "'", '“': "\"", '”': "\"",
+}
+
+// Symbols on the standard 31 key layout
+const layoutSymbols = ",./;'"
+
+// Normalization presets by language
+var Languages = map[string]Normalization{
+	"en": {
+		FoldCase:  true,
+		Unshift:   UsShiftMap,
+		Decompose: AccentMap,
+		Keep:      "abcdefghijklmnopqrstuvwxyz" + layoutSymbols,
+	},
+	"de": {
+		FoldCase:  true,
+		Unshift:   UsShiftMap,
+		Decompose: AccentMap,
+		Keep:      "abcdefghijklmnopqrstuvwxyzäöüß" + layoutSymbols,
+	},
+	"nordic": {
+		FoldCase:  true,
+		Unshift:   UsShiftMap,
+		Decompose: AccentMap,
+		Keep:      "abcdefghijklmnopqrstuvwxyzåäæöø" + layoutSymbols,
+	},
+	"ru": {
+		FoldCase:  true,
+		Unshift:   UsShiftMap,
+		Decompose: AccentMap,
+		Keep:      "абвгдеёжзийклмнопрстуфхцчшщъыьэюя" + layoutSymbols,
+	},
+}
+
+// Returns the normalization preset of a language
+func LanguageNormalization(lang string) (Normalization, error) {
+	norm, ok := Languages[lang]
+	if !ok {
+		return Normalization{}, fmt.Errorf("unknown language %q", lang)
+	}
+	return norm, nil
+}
+
+func (n Normalization) keeps(r rune) bool {
+	return n.Keep == "" || strings.ContainsRune(n.Keep, r)
+}
+
+func (n Normalization) base(r rune) rune {
+	if n.FoldCase {
+		r = unicode.ToLower(r)
+	}
+	if base, ok := n.Unshift[r]; ok {
+		r = base
+	}
+	return r
+}
+
+// Normalizes a single character. The result is empty if the character is dropped.
+func (n Normalization) NormalizeRune(r rune) string {
+	r = n.base(r)
+	if n.keeps(r) {
+		return string(r)
+	}
+
+	decomposed, ok := n.Decompose[r]
+	if !ok {
+		return ""
+	}
+
+	out := make([]rune, 0, len(decomposed))
+	for _, c := range decomposed {
+		c = n.base(c)
+		if !n.keeps(c) {
+			return ""
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// Normalizes a line of text, returning the runs of kept characters.
+// Dropped characters split the line, so runs never span them.
+func (n Normalization) Normalize(line string) []string {
+	segments := []string{}
+	var current strings.Builder
+
+	for _, r := range line {
+		normalized := n.NormalizeRune(r)
+		if normalized == "" {
+			if current.Len() > 0 {
+				segments = append(segments, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteString(normalized)
+	}
+
+	if current.Len() > 0 {
+		segments = append(segments, current.String())
+	}
+
+	return segments
+}
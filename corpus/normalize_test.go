@@ -0,0 +1,54 @@
+package corpus
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		lang     string
+		line     string
+		expected []string
+	}{
+		{"en", "Hello: World?", []string{"hello;", "world/"}},
+		{"en", "Café Straße", []string{"cafe", "strasse"}},
+		{"en", "don’t (stop)", []string{"don't", "stop"}},
+		{"de", "Grüße, Straße!", []string{"grüße,", "straße"}},
+		{"nordic", "Blåbærsyltetøy", []string{"blåbærsyltetøy"}},
+		{"ru", "Привет, Мир", []string{"привет,", "мир"}},
+	}
+
+	for _, test := range tests {
+		norm, err := LanguageNormalization(test.lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := norm.Normalize(test.line)
+		if !slices.Equal(out, test.expected) {
+			t.Errorf("Normalize(%q) [%s] = %q but want %q", test.line, test.lang, out, test.expected)
+		}
+	}
+
+	if _, err := LanguageNormalization("xx"); err == nil {
+		t.Error("LanguageNormalization(\"xx\") should fail")
+	}
+}
+
+func TestFromText(t *testing.T) {
+	cf, err := FromText("../CharFreqData/google-10000-english-usa.txt", Languages["en"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cf.Chars['e'] == 0 || cf.Bigrams["th"] == 0 || cf.Trigrams["the"] == 0 {
+		t.Errorf("FromText() is missing common n-grams")
+	}
+
+	for c := range cf.Chars {
+		if c < 'a' || c > 'z' {
+			t.Errorf("FromText() kept unsupported character %q", c)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package formats converts layouts from and to the files of other keyboard layout tools.
+package formats
+
+import (
+	"fmt"
+	"io"
+	kbd "kbannealing/keyboard"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// A named keyboard read from or written to a file. Only the 31 keys of the layout are converted.
+type Layout struct {
+	Name     string
+	Keyboard *kbd.Keyboard
+}
+
+// Name written for layouts without a name, for formats that need one
+const defaultName = "layout"
+
+// A file format of another tool. Keymaps of operating systems and firmware have no Import.
+type Format struct {
+	Name   string
+	Import func(io.Reader) (Layout, error)
+	Export func(io.Writer, Layout) error
+}
+
+var Formats = []Format{
+	{"genkey", ImportGenkey, ExportGenkey},
+	{"oxeylyzer", ImportOxeylyzer, ExportOxeylyzer},
+	{"kle", ImportKLE, ExportKLE},
+	{"xkb", ImportXKB, ExportXKB},
+	{"keylayout", nil, ExportKeylayout},
+	{"klc", nil, ExportKLC},
+	{"qmk", nil, ExportQMK},
+}
+
+func Lookup(name string) (Format, error) {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		if f.Name == name {
+			return f, nil
+		}
+		names[i] = f.Name
+	}
+	return Format{}, fmt.Errorf("unknown format %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+// Number of keys on each row of a layout in row form
+var rowLengths = []int{10, 11, 10}
+
+// Splits a layout in row form into its rows
+func rows(layout string) [][]rune {
+	keys := []rune(layout)
+	out := [][]rune{}
+	for _, n := range rowLengths {
+		out = append(out, keys[:n])
+		keys = keys[n:]
+	}
+	return out
+}
+
+// Joins rows into a keyboard. The home row may leave out its 11th key, which becomes the
+// apostrophe if it is not on the layout yet, and an empty key otherwise.
+func joinRows(keys [][]rune) (*kbd.Keyboard, error) {
+	if len(keys) != len(rowLengths) {
+		return nil, fmt.Errorf("layout needs %d rows, got %d", len(rowLengths), len(keys))
+	}
+
+	extra := len(keys[1]) == rowLengths[1]-1
+	layout := []rune{}
+	for i, row := range keys {
+		if i == 1 && extra {
+			row = append(slices.Clone(row), kbd.Blank)
+		}
+		if len(row) != rowLengths[i] {
+			return nil, fmt.Errorf("row %d needs %d keys, got %d", i+1, rowLengths[i], len(row))
+		}
+		layout = append(layout, row...)
+	}
+
+	if extra && !slices.Contains(layout, '\'') {
+		layout[rowLengths[0]+rowLengths[1]-1] = '\''
+	}
+	return kbd.ParseKeyboard(string(layout))
+}
+
+// Finger typing each column of a row, the index fingers also type the inner columns
+func columnFinger(col int) int {
+	switch {
+	case col <= 3:
+		return col
+	case col == 4:
+		return 3
+	case col <= 6:
+		return 4
+	default:
+		return min(col-2, 7)
+	}
+}
+
+// Parses a row of keys separated by whitespace, where every key is one character
+func parseRow(line string) ([]rune, error) {
+	row := []rune{}
+	for _, field := range strings.Fields(line) {
+		if utf8.RuneCountInString(field) != 1 {
+			return nil, fmt.Errorf("key %q is not one character", field)
+		}
+		r, _ := utf8.DecodeRuneInString(field)
+		row = append(row, r)
+	}
+	return row, nil
+}
@@ -0,0 +1,147 @@
+package formats
+
+import (
+	"bytes"
+	kbd "kbannealing/keyboard"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	layouts := []Layout{
+		{"qwerty", kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")},
+		{"german", kbd.NewKeyboard("qwertzuiopasdfghjklöäyxcvbnm,.·")},
+	}
+
+	for _, format := range Formats {
+		if format.Import == nil {
+			continue
+		}
+		for _, l := range layouts {
+			var buf bytes.Buffer
+			if err := format.Export(&buf, l); err != nil {
+				t.Fatalf("%s export of %s: %s", format.Name, l.Name, err)
+			}
+			got, err := format.Import(&buf)
+			if err != nil {
+				t.Fatalf("%s import of %s: %s", format.Name, l.Name, err)
+			}
+
+			// oxeylyzer files have no name
+			wantName := l.Name
+			if format.Name == "oxeylyzer" {
+				wantName = ""
+			}
+			if got.Name != wantName || got.Keyboard.Layout != l.Keyboard.Layout {
+				t.Errorf("%s round trip = %q %s but want %q %s", format.Name, got.Name, got.Keyboard.Layout, wantName, l.Keyboard.Layout)
+			}
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		format string
+		file   string
+		name   string
+		layout string
+	}{
+		{"genkey", "Graphite\nb l d w z ' f o u j\nn r t s g y h a e i ,\nq x m c v k p . - /\n" +
+			"0 1 2 3 3 6 6 7 8 9\n0 1 2 3 3 6 6 7 8 9 9\n0 1 2 3 3 6 6 7 8 9\n",
+			"Graphite", "bldwz'foujnrtsgyhaei,qxmcvkp.-/"},
+		{"oxeylyzer", "q w e r t  y u i o p\na s d f g  h j k l ;\n\nz x c v b  n m , . /\n",
+			"", "qwertyuiopasdfghjkl;'zxcvbnm,./"},
+		{"oxeylyzer", "' w e r t  y u i o p\na s d f g  h j k l ;\nz x c v b  n m , . /\n",
+			"", "'wertyuiopasdfghjkl;·zxcvbnm,./"},
+		{"kle", `[{"name":"qwerty","author":"someone"},
+			[{"a":4},"Q","W","E","R","T","Y","U","I","O","P"],
+			[{"x":0.25},"A","S","D","F","G","H","J","K","L",":\n;","\"\n'"],
+			[{"x":0.75},"Z","X","C","V","B","N","M","<\n,",">\n.","?\n/"]]`,
+			"qwerty", "qwertyuiopasdfghjkl;'zxcvbnm,./"},
+		{"xkb", `// a comment
+default partial alphanumeric_keys
+xkb_symbols "test" {
+    include "us(basic)"
+    key <AE01> { [ 1, exclam ] };
+    key <AD01> { [ q, Q ] }; key <AD02> { [ w, W ] }; key <AD03> { [ e, E ] }; key <AD04> { [ r, R ] };
+    key <AD05> { [ t, T ] }; key <AD06> { [ y, Y ] }; key <AD07> { [ u, U ] }; key <AD08> { [ i, I ] };
+    key <AD09> { [ o, O ] }; key <AD10> { [ p, P ] };
+    key <AC01> { [ a ] }; key <AC02> { [ s ] }; key <AC03> { [ d ] }; key <AC04> { [ f ] };
+    key <AC05> { [ g ] }; key <AC06> { [ h ] }; key <AC07> { [ j ] }; key <AC08> { [ k ] };
+    key <AC09> { [ l ] }; key <AC10> { type[Group1] = "FOUR_LEVEL", [ U00F6, U00D6 ] };
+    key <AC11> { [ NoSymbol ] };
+    key <AB01> { [ z ] }; key <AB02> { [ x ] }; key <AB03> { [ c ] }; key <AB04> { [ v ] };
+    key <AB05> { [ b ] }; key <AB06> { [ n ] }; key <AB07> { [ m ] }; key <AB08> { [ comma ] };
+    key <AB09> { [ period ] }; key <AB10> { [ slash ] };
+};
+xkb_symbols "other" {
+    key <AD01> { [ x ] };
+};`, "test", "qwertyuiopasdfghjklö·zxcvbnm,./"},
+	}
+
+	for _, test := range tests {
+		format, err := Lookup(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := format.Import(strings.NewReader(test.file))
+		if err != nil {
+			t.Errorf("%s import = %s", test.format, err)
+			continue
+		}
+		if got.Name != test.name || got.Keyboard.Layout != test.layout {
+			t.Errorf("%s import = %q %s but want %q %s", test.format, got.Name, got.Keyboard.Layout, test.name, test.layout)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		file   string
+	}{
+		{"genkey", ""},
+		{"genkey", "name\nq w e r t y u i o p\na s d f g h j k l ;\n"},
+		{"oxeylyzer", "q w e r t y u i o\na s d f g h j k l ;\nz x c v b n m , . /\n"},
+		{"oxeylyzer", "q w e r t y u i o p\na s d f g h j k l ;\nz x c v b n m , . //\n"},
+		{"oxeylyzer", "q w e r t y u i o p\na s d f g h j k l ;\nz x c v b n m , . q\n"},
+		{"kle", `[["Tab","Q"]]`},
+		{"kle", `{"name":"x"}`},
+		{"xkb", `xkb_symbols "x" { key <AD01> { [ q ] }; };`},
+		{"xkb", `xkb_symbols "x" { key <AD01> { [ notakeysym ] }; };`},
+	}
+
+	for _, test := range tests {
+		format, _ := Lookup(test.format)
+		if _, err := format.Import(strings.NewReader(test.file)); err == nil {
+			t.Errorf("%s import of %q should fail", test.format, test.file)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	l := Layout{"qwerty", kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"genkey", []string{"qwerty\nq w e r t y u i o p\na s d f g h j k l ;\n", "0 1 2 3 3 6 6 7 8 9\n"}},
+		{"oxeylyzer", []string{"q w e r t  y u i o p\n"}},
+		{"kle", []string{`[{"x":0.25},"A",`, `"<\n,"`}},
+		{"xkb", []string{`xkb_symbols "qwerty"`, "key <AC10> { [ semicolon, colon ] };", "key <AC11> { [ apostrophe, quotedbl ] };"}},
+	}
+
+	for _, test := range tests {
+		format, _ := Lookup(test.format)
+		var buf bytes.Buffer
+		if err := format.Export(&buf, l); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s export = %s but want it to contain %s", test.format, buf.String(), want)
+			}
+		}
+	}
+}
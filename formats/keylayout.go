@@ -0,0 +1,133 @@
+package formats
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strings"
+)
+
+// macOS .keylayout files map virtual key codes to characters in one key map per modifier state.
+// Copy the file to ~/Library/Keyboard Layouts and log in again to use it.
+
+// Virtual key codes of the ANSI keys of a layout in row form
+var macKeyCodes = []int{
+	12, 13, 14, 15, 17, 16, 32, 34, 31, 35,
+	0, 1, 2, 3, 5, 4, 38, 40, 37, 41, 39,
+	6, 7, 8, 9, 11, 45, 46, 43, 47, 44,
+}
+
+// Keys around the layout that keep their characters of the US layout, unshifted and shifted
+var macOtherKeys = map[int][2]rune{
+	50: {'`', '~'}, 18: {'1', '!'}, 19: {'2', '@'}, 20: {'3', '#'}, 21: {'4', '$'}, 23: {'5', '%'},
+	22: {'6', '^'}, 26: {'7', '&'}, 28: {'8', '*'}, 25: {'9', '('}, 29: {'0', ')'}, 27: {'-', '_'},
+	24: {'=', '+'}, 33: {'[', '{'}, 30: {']', '}'}, 42: {'\\', '|'}, 49: {' ', ' '},
+}
+
+// Keys typing control characters, the same in every key map
+var macControlKeys = map[int]rune{
+	36: '\r', 48: '\t', 51: '\b', 53: 0x1b, 76: 0x03, 117: 0x7f,
+	115: 0x01, 119: 0x04, 116: 0x0b, 121: 0x0c, 123: 0x1c, 124: 0x1d, 125: 0x1f, 126: 0x1e,
+}
+
+// Escapes characters of output attributes, everything but ASCII letters and digits and
+// non-ASCII characters is written as a character reference
+func macOutput(r rune) string {
+	if r > 0x7f || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+		return string(r)
+	}
+	return fmt.Sprintf("&#x%04X;", r)
+}
+
+// Keyboard ids of custom layouts are negative, this one is derived from the name so it stays
+// the same when the file is written again
+func macID(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return -int(h.Sum32()%32000) - 2
+}
+
+func ExportKeylayout(w io.Writer, l Layout) error {
+	name := cmp.Or(l.Name, defaultName)
+	levels := keyLevels(l.Keyboard)
+
+	// characters by key code for the base, shift, caps lock and option key maps
+	maps := make([]map[int]rune, 4)
+	for i := range maps {
+		maps[i] = map[int]rune{}
+		for code, r := range macControlKeys {
+			maps[i][code] = r
+		}
+	}
+	for code, keys := range macOtherKeys {
+		maps[0][code] = keys[0]
+		maps[1][code] = keys[1]
+		maps[2][code] = keys[0]
+	}
+	maps[3][49] = ' '
+
+	for i, code := range macKeyCodes {
+		maps[0][code] = levels.base[i]
+		maps[1][code] = levels.shifted[i]
+		maps[2][code] = levels.base[i]
+		if capsLock(levels.base[i]) {
+			maps[2][code] = levels.shifted[i]
+		}
+		if levels.symbols != nil {
+			maps[3][code] = levels.symbols[i]
+		}
+	}
+	modifiers := []string{"", "anyShift caps?", "caps", "anyOption anyShift? caps?"}
+	if levels.symbols == nil {
+		maps = maps[:3]
+		modifiers = modifiers[:3]
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, `<?xml version="1.1" encoding="UTF-8"?>`)
+	fmt.Fprintln(out, `<!DOCTYPE keyboard SYSTEM "file://localhost/System/Library/DTDs/KeyboardLayout.dtd">`)
+	fmt.Fprintf(out, "<keyboard group=\"126\" id=\"%d\" name=\"%s\" maxout=\"1\">\n", macID(name), xmlEscape(name))
+	fmt.Fprintln(out, "    <layouts>")
+	fmt.Fprintln(out, `        <layout first="0" last="17" mapSet="ANSI" modifiers="modifiers"/>`)
+	fmt.Fprintln(out, "    </layouts>")
+	fmt.Fprintln(out, `    <modifierMap id="modifiers" defaultIndex="0">`)
+	for i, keys := range modifiers {
+		fmt.Fprintf(out, "        <keyMapSelect mapIndex=\"%d\">\n", i)
+		fmt.Fprintf(out, "            <modifier keys=\"%s\"/>\n", keys)
+		fmt.Fprintln(out, "        </keyMapSelect>")
+	}
+	fmt.Fprintln(out, "    </modifierMap>")
+	fmt.Fprintln(out, `    <keyMapSet id="ANSI">`)
+	for i, keys := range maps {
+		fmt.Fprintf(out, "        <keyMap index=\"%d\">\n", i)
+		codes := make([]int, 0, len(keys))
+		for code := range keys {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			if typed(keys[code]) {
+				fmt.Fprintf(out, "            <key code=\"%d\" output=\"%s\"/>\n", code, macOutput(keys[code]))
+			}
+		}
+		fmt.Fprintln(out, "        </keyMap>")
+	}
+	fmt.Fprintln(out, "    </keyMapSet>")
+	fmt.Fprintln(out, "</keyboard>")
+	return out.Flush()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == '&' || r == '<' || r == '>' || r == '"' || r == '\'' {
+			b.WriteString(macOutput(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package formats
+
+import (
+	kbd "kbannealing/keyboard"
+	"unicode"
+)
+
+// Keymaps of operating systems and keyboard firmware can only be written. Keys have the
+// character of the layout and its shifted character as on the US layout, and the first symbol
+// layer is typed with AltGr, Option or a firmware layer. Adaptive keys only work in firmware,
+// the other keymaps leave them empty.
+
+// Characters of each level of a layout in row form, Blank for keys without a character
+type levels struct {
+	base    []rune
+	shifted []rune
+	// keys of the first layer that is not shifted, nil if there is none
+	symbols []rune
+}
+
+func keyLevels(kb *kbd.Keyboard) levels {
+	l := levels{base: []rune(kb.Layout)}
+	for _, r := range l.base {
+		shifted := kbd.ShiftRune(r)
+		if isAdaptive(r) {
+			shifted = kbd.Blank
+		}
+		l.shifted = append(l.shifted, shifted)
+	}
+	for _, layer := range kb.Layers {
+		if !layer.Shifted {
+			l.symbols = []rune(layer.Keys)
+			break
+		}
+	}
+	return l
+}
+
+func isAdaptive(r rune) bool {
+	return r == kbd.RepeatKey || r == kbd.MagicKey
+}
+
+// Whether a key types r in an OS keymap
+func typed(r rune) bool {
+	return r != kbd.Blank && !isAdaptive(r)
+}
+
+// Whether caps lock types the shifted character of a key
+func capsLock(r rune) bool {
+	return unicode.IsLetter(r) && unicode.ToUpper(r) != r
+}
@@ -0,0 +1,75 @@
+package formats
+
+import (
+	"bytes"
+	"flag"
+	kbd "kbannealing/keyboard"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Write the golden files of the keymap tests")
+
+func TestKeymaps(t *testing.T) {
+	symbols := "!@#$%^&*()···········-=+_{}<>[]"
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjklö'zxcvbnm,."+string(kbd.MagicKey)).
+		WithThumbs(" "+string(kbd.RepeatKey)).
+		WithLayers(kbd.ShiftLayer(), kbd.SymbolLayer("symbols", symbols, '◆', 7)).
+		WithMagic(kbd.MagicRules{'e': 'a', 'o': 'u'})
+	l := Layout{"Test & Co", kb}
+
+	for _, golden := range []struct {
+		format string
+		file   string
+	}{
+		{"xkb", "keymap.xkb"},
+		{"keylayout", "keymap.keylayout"},
+		{"klc", "keymap.klc"},
+		{"qmk", "keymap.c"},
+	} {
+		format, err := Lookup(golden.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := format.Export(&buf, l); err != nil {
+			t.Fatalf("%s export: %s", golden.format, err)
+		}
+
+		path := filepath.Join("testdata", golden.file)
+		if *update {
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s export differs from %s, run go test ./formats -update and check the diff", golden.format, path)
+		}
+	}
+}
+
+func TestKeymapsWithoutLayers(t *testing.T) {
+	l := Layout{"qwerty", kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")}
+
+	// the xkb keymap is the export of the xkb format, so it reads back
+	var buf bytes.Buffer
+	if err := ExportXKB(&buf, l); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ImportXKB(&buf)
+	if err != nil || got.Keyboard.Layout != l.Keyboard.Layout {
+		t.Errorf("ImportXKB(ExportXKB()) = %v, %v but want %s", got.Keyboard, err, l.Keyboard.Layout)
+	}
+
+	for _, format := range Formats {
+		buf.Reset()
+		if err := format.Export(&buf, l); err != nil || buf.Len() == 0 {
+			t.Errorf("%s export = %d bytes, %v", format.Name, buf.Len(), err)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package formats
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	kbd "kbannealing/keyboard"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Windows KLC files are the source files of the Microsoft Keyboard Layout Creator, which
+// builds the installer of the layout. They are UTF-16 text with a byte order mark.
+
+// Scan codes of the keys of a layout in row form
+var klcScanCodes = []int{
+	0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19,
+	0x1e, 0x1f, 0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28,
+	0x2c, 0x2d, 0x2e, 0x2f, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35,
+}
+
+// Keys around the layout with their characters of the US layout, unshifted and shifted
+var klcOtherKeys = []struct {
+	scanCode int
+	keys     [2]rune
+}{
+	{0x29, [2]rune{'`', '~'}}, {0x02, [2]rune{'1', '!'}}, {0x03, [2]rune{'2', '@'}}, {0x04, [2]rune{'3', '#'}},
+	{0x05, [2]rune{'4', '$'}}, {0x06, [2]rune{'5', '%'}}, {0x07, [2]rune{'6', '^'}}, {0x08, [2]rune{'7', '&'}},
+	{0x09, [2]rune{'8', '*'}}, {0x0a, [2]rune{'9', '('}}, {0x0b, [2]rune{'0', ')'}}, {0x0c, [2]rune{'-', '_'}},
+	{0x0d, [2]rune{'=', '+'}}, {0x1a, [2]rune{'[', '{'}}, {0x1b, [2]rune{']', '}'}}, {0x2b, [2]rune{'\\', '|'}},
+	{0x39, [2]rune{' ', ' '}}, {0x56, [2]rune{'\\', '|'}},
+}
+
+// Virtual keys of the US layout by character. Shortcuts follow the virtual keys, so they
+// follow the characters when the key has one of its own.
+var klcVirtualKeys = map[rune]string{
+	';': "OEM_1", '\'': "OEM_7", ',': "OEM_COMMA", '.': "OEM_PERIOD", '/': "OEM_2", '[': "OEM_4",
+	']': "OEM_6", '\\': "OEM_5", '`': "OEM_3", '-': "OEM_MINUS", '=': "OEM_PLUS", ' ': "SPACE",
+}
+
+// Virtual keys for keys whose character has none or whose virtual key is taken
+var klcFreeVirtualKeys = []string{
+	"OEM_1", "OEM_7", "OEM_COMMA", "OEM_PERIOD", "OEM_2", "OEM_4", "OEM_6", "OEM_5", "OEM_3",
+	"OEM_MINUS", "OEM_PLUS", "OEM_102", "OEM_8",
+}
+
+// Control characters typed with Ctrl, as on the US layout
+var klcControl = map[rune]rune{'[': 0x1b, ']': 0x1d, '\\': 0x1c, ' ': ' '}
+
+func klcChar(r rune) string {
+	if !typed(r) {
+		return "-1"
+	}
+	if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return string(r)
+	}
+	return fmt.Sprintf("%04x", r)
+}
+
+// Short name of the layout, at most 8 ASCII letters and digits
+func klcShortName(name string) string {
+	short := []rune{}
+	for _, r := range name {
+		if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) && len(short) < 8 {
+			short = append(short, r)
+		}
+	}
+	return cmp.Or(string(short), "kbanneal")
+}
+
+var klcKeyNames = `KEYNAME
+
+01	Esc
+0e	Backspace
+0f	Tab
+1c	Enter
+1d	Ctrl
+2a	Shift
+36	"Right Shift"
+37	"Num *"
+38	Alt
+39	Space
+3a	"Caps Lock"
+3b	F1
+3c	F2
+3d	F3
+3e	F4
+3f	F5
+40	F6
+41	F7
+42	F8
+43	F9
+44	F10
+45	Pause
+46	"Scroll Lock"
+47	"Num 7"
+48	"Num 8"
+49	"Num 9"
+4a	"Num -"
+4b	"Num 4"
+4c	"Num 5"
+4d	"Num 6"
+4e	"Num +"
+4f	"Num 1"
+50	"Num 2"
+51	"Num 3"
+52	"Num 0"
+53	"Num Del"
+54	"Sys Req"
+57	F11
+58	F12
+
+KEYNAME_EXT
+
+1c	"Num Enter"
+1d	"Right Ctrl"
+35	"Num /"
+37	"Prnt Scrn"
+38	"Right Alt"
+45	"Num Lock"
+46	Break
+47	Home
+48	Up
+49	"Page Up"
+4b	Left
+4d	Right
+4f	End
+50	Down
+51	"Page Down"
+52	Insert
+53	Delete
+5b	"Left Windows"
+5c	"Right Windows"
+5d	Application
+`
+
+func ExportKLC(w io.Writer, l Layout) error {
+	name := cmp.Or(l.Name, defaultName)
+	levels := keyLevels(l.Keyboard)
+
+	type klcKey struct {
+		scanCode int
+		base     rune
+		shifted  rune
+		symbol   rune
+	}
+	keys := []klcKey{}
+	for i, scanCode := range klcScanCodes {
+		key := klcKey{scanCode, levels.base[i], levels.shifted[i], kbd.Blank}
+		if levels.symbols != nil {
+			key.symbol = levels.symbols[i]
+		}
+		keys = append(keys, key)
+	}
+	for _, other := range klcOtherKeys {
+		keys = append(keys, klcKey{other.scanCode, other.keys[0], other.keys[1], kbd.Blank})
+	}
+
+	// the keys of the layout pick their virtual keys first
+	used := map[string]bool{}
+	virtualKeys := make([]string, len(keys))
+	for i, key := range keys {
+		vk := klcVirtualKeys[key.base]
+		if key.base < 0x80 && (unicode.IsLetter(key.base) || unicode.IsDigit(key.base)) {
+			vk = string(unicode.ToUpper(key.base))
+		}
+		if vk != "" && !used[vk] {
+			virtualKeys[i] = vk
+			used[vk] = true
+		}
+	}
+	free := slices.Clone(klcFreeVirtualKeys)
+	for r := 'A'; r <= 'Z'; r++ {
+		free = append(free, string(r))
+	}
+	for i := range keys {
+		for virtualKeys[i] == "" {
+			if len(free) == 0 {
+				return fmt.Errorf("no virtual key left for key %02x", keys[i].scanCode)
+			}
+			if !used[free[0]] {
+				virtualKeys[i] = free[0]
+				used[free[0]] = true
+			}
+			free = free[1:]
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "KBD\t%s\t%q\n\n", klcShortName(name), name)
+	fmt.Fprintf(&b, "COPYRIGHT\t\"(c) %s\"\n\n", name)
+	fmt.Fprintf(&b, "COMPANY\t\"kbannealing\"\n\n")
+	fmt.Fprintf(&b, "LOCALENAME\t\"en-US\"\n\n")
+	fmt.Fprintf(&b, "LOCALEID\t\"00000409\"\n\n")
+	fmt.Fprintf(&b, "VERSION\t1.0\n\n")
+	fmt.Fprintf(&b, "SHIFTSTATE\n\n0\t//Column 4\n1\t//Column 5 : Shft\n2\t//Column 6 :       Ctrl\n")
+	header := "//SC\tVK_\t\tCap\t0\t1\t2"
+	if levels.symbols != nil {
+		fmt.Fprintf(&b, "6\t//Column 7 :       Ctrl Alt\n")
+		header += "\t6"
+	}
+	fmt.Fprintf(&b, "\nLAYOUT\t\t;an extra '@' at the end is a dead key\n\n%s\n\n", header)
+
+	for i, key := range keys {
+		caps := 0
+		if capsLock(key.base) {
+			caps = 1
+		}
+		control := klcChar(klcControl[key.base])
+		if _, ok := klcControl[key.base]; !ok {
+			control = "-1"
+		}
+		fmt.Fprintf(&b, "%02x\t%s\t\t%d\t%s\t%s\t%s", key.scanCode, virtualKeys[i], caps,
+			klcChar(key.base), klcChar(key.shifted), control)
+		if levels.symbols != nil {
+			fmt.Fprintf(&b, "\t%s", klcChar(key.symbol))
+		}
+		chars := []string{}
+		for _, r := range []rune{key.base, key.shifted, key.symbol} {
+			if typed(r) && r != ' ' {
+				chars = append(chars, string(r))
+			}
+		}
+		if len(chars) > 0 {
+			fmt.Fprintf(&b, "\t// %s", strings.Join(chars, ", "))
+		}
+		fmt.Fprintln(&b)
+	}
+
+	fmt.Fprintf(&b, "\n%s\nDESCRIPTIONS\n\n0409\t%s\n\n", klcKeyNames, name)
+	fmt.Fprintf(&b, "LANGUAGENAMES\n\n0409\tEnglish (United States)\n\nENDKBD\n")
+
+	return writeUTF16(w, strings.ReplaceAll(b.String(), "\n", "\r\n"))
+}
+
+// Writes s as little endian UTF-16 with a byte order mark
+func writeUTF16(w io.Writer, s string) error {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xfe})
+	for _, unit := range utf16.Encode([]rune(s)) {
+		binary.Write(&buf, binary.LittleEndian, unit)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,133 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	kbd "kbannealing/keyboard"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KLE (keyboard-layout-editor.com) raw data is a list of rows, where strings are key legends
+// and objects change the properties of the following keys, such as the x offset. Only the 31
+// keys of the layout are read and written, without thumb keys.
+
+// Properties of the keyboard, the first element of the data if it is an object
+type kleMeta struct {
+	Name string `json:"name,omitempty"`
+}
+
+func ImportKLE(r io.Reader) (Layout, error) {
+	var data []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return Layout{}, fmt.Errorf("invalid KLE data: %w", err)
+	}
+
+	var l Layout
+	keys := [][]rune{}
+	for i, raw := range data {
+		var row []json.RawMessage
+		if err := json.Unmarshal(raw, &row); err != nil {
+			var meta kleMeta
+			if i > 0 || json.Unmarshal(raw, &meta) != nil {
+				return Layout{}, fmt.Errorf("invalid KLE row %d", i+1)
+			}
+			l.Name = meta.Name
+			continue
+		}
+
+		keyRow := []rune{}
+		for _, item := range row {
+			var legend string
+			// key properties are objects, only the legends matter here
+			if json.Unmarshal(item, &legend) != nil {
+				continue
+			}
+			key, err := kleKey(legend)
+			if err != nil {
+				return Layout{}, fmt.Errorf("row %d: %w", len(keys)+1, err)
+			}
+			keyRow = append(keyRow, key)
+		}
+		keys = append(keys, keyRow)
+	}
+
+	var err error
+	l.Keyboard, err = joinRows(keys)
+	return l, err
+}
+
+// Returns the character of a key legend. Legends are lines from the top left, so a key with
+// two lines shows the shifted character above the base character. Letters are shown uppercase.
+func kleKey(legend string) (rune, error) {
+	lines := strings.Split(legend, "\n")
+	base := lines[0]
+	if len(lines) > 1 && lines[1] != "" {
+		base = lines[1]
+	}
+	if base == "" {
+		return kbd.Blank, nil
+	}
+	if utf8.RuneCountInString(base) != 1 {
+		return 0, fmt.Errorf("key %q is not one character", base)
+	}
+	r, _ := utf8.DecodeRuneInString(base)
+	return unicode.ToLower(r), nil
+}
+
+func kleLegend(r rune) string {
+	if r == kbd.Blank {
+		return ""
+	}
+	if unicode.IsLetter(r) {
+		return string(unicode.ToUpper(r))
+	}
+	if shifted := kbd.ShiftRune(r); shifted != kbd.Blank {
+		return string(shifted) + "\n" + string(r)
+	}
+	return string(r)
+}
+
+func ExportKLE(w io.Writer, l Layout) error {
+	out := bufio.NewWriter(w)
+	meta, err := marshal(kleMeta{l.Name})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "[\n%s", meta)
+
+	start := 0
+	for _, row := range rows(l.Keyboard.Layout) {
+		items := []any{}
+		if x := kbd.KeyPosition(start).X; x != 0 {
+			items = append(items, map[string]float64{"x": x})
+		}
+		for _, r := range row {
+			items = append(items, kleLegend(r))
+		}
+		start += len(row)
+
+		data, err := marshal(items)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, ",\n%s", data)
+	}
+	fmt.Fprintln(out, "\n]")
+	return out.Flush()
+}
+
+// Marshals v without escaping <, > and &, which are common legends
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,150 @@
+package formats
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	kbd "kbannealing/keyboard"
+	"slices"
+	"strings"
+)
+
+// QMK keymap.c files for a split keyboard of 3 rows of 6 keys and 3 thumb keys per hand, such
+// as the Corne. The outer columns hold the 11th key of the home row and the modifiers, and the
+// thumb keys of the layout are the inner thumb keys. The repeat and magic keys are the repeat
+// and alternate repeat keys of QMK, which need REPEAT_KEY_ENABLE = yes in rules.mk, and
+// characters outside of ASCII need UNICODE_ENABLE = yes.
+
+var qmkKeycodes = map[rune]string{
+	' ': "KC_SPC", ';': "KC_SCLN", '\'': "KC_QUOT", ',': "KC_COMM", '.': "KC_DOT", '/': "KC_SLSH",
+	'-': "KC_MINS", '=': "KC_EQL", '[': "KC_LBRC", ']': "KC_RBRC", '\\': "KC_BSLS", '`': "KC_GRV",
+	'~': "KC_TILD", '!': "KC_EXLM", '@': "KC_AT", '#': "KC_HASH", '$': "KC_DLR", '%': "KC_PERC",
+	'^': "KC_CIRC", '&': "KC_AMPR", '*': "KC_ASTR", '(': "KC_LPRN", ')': "KC_RPRN", '_': "KC_UNDS",
+	'+': "KC_PLUS", '{': "KC_LCBR", '}': "KC_RCBR", '|': "KC_PIPE", ':': "KC_COLN", '"': "KC_DQUO",
+	'<': "KC_LABK", '>': "KC_RABK", '?': "KC_QUES",
+	kbd.Blank: "XXXXXXX", kbd.RepeatKey: "QK_REP", kbd.MagicKey: "QK_AREP",
+}
+
+func qmkKeycode(r rune) string {
+	switch {
+	case qmkKeycodes[r] != "":
+		return qmkKeycodes[r]
+	case 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+		return "KC_" + strings.ToUpper(string(r))
+	case 'A' <= r && r <= 'Z':
+		return "S(KC_" + string(r) + ")"
+	default:
+		return fmt.Sprintf("UC(0x%04X)", r)
+	}
+}
+
+// Writes one LAYOUT_split_3x6_3 call of keycodes in row form, with the outer columns and thumb keys given
+func qmkLayout(out *bufio.Writer, layer string, keys []string, outer [3][2]string, thumbs [6]string) {
+	fmt.Fprintf(out, "    [%s] = LAYOUT_split_3x6_3(\n", layer)
+	pad := func(codes ...string) string {
+		s := make([]string, len(codes))
+		for i, code := range codes {
+			s[i] = fmt.Sprintf("%-8s", code+",")
+		}
+		return strings.TrimRight(strings.Join(s, " "), " ")
+	}
+
+	keys = slices.Clone(keys)
+	for row, n := range []int{10, 11, 10} {
+		line := append([]string{outer[row][0]}, keys[:10]...)
+		if n == 10 {
+			line = append(line, outer[row][1])
+		} else {
+			line = append(line, keys[10])
+		}
+		keys = keys[n:]
+		fmt.Fprintf(out, "        %s\n", pad(line...))
+	}
+	fmt.Fprintf(out, "        %s%s\n", strings.Repeat(" ", 27), strings.TrimSuffix(pad(thumbs[:]...), ","))
+	fmt.Fprintln(out, "    ),")
+}
+
+func ExportQMK(w io.Writer, l Layout) error {
+	name := cmp.Or(l.Name, defaultName)
+	kb := l.Keyboard
+	levels := keyLevels(kb)
+
+	thumbs := [6]string{"KC_LGUI", "KC_ESC", "KC_SPC", "KC_ENT", "KC_DEL", "KC_RALT"}
+	for i, r := range []rune(kb.Thumbs) {
+		if r != kbd.Blank && i < 2 {
+			thumbs[2+i] = qmkKeycode(r)
+		}
+	}
+	// the symbol layer key is the middle thumb key of the hand of the finger pressing it
+	var symbolThumb int
+	if levels.symbols != nil {
+		symbolThumb = 4
+		for _, layer := range kb.Layers {
+			if !layer.Shifted && kbd.FingerOnLeft(layer.Activators[0].Finger) {
+				symbolThumb = 1
+			}
+		}
+		thumbs[symbolThumb] = "MO(SYMBOLS)"
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "// QMK keymap of %s for a split 3x6 keyboard with 3 thumb keys per hand\n", name)
+	if kb.HasMagic() {
+		fmt.Fprintln(out, "// The adaptive keys need REPEAT_KEY_ENABLE = yes in rules.mk")
+	}
+	if slices.ContainsFunc(append(slices.Clone(levels.base), levels.symbols...), func(r rune) bool {
+		return strings.HasPrefix(qmkKeycode(r), "UC(")
+	}) {
+		fmt.Fprintln(out, "// Characters outside of ASCII need UNICODE_ENABLE = yes in rules.mk")
+	}
+	fmt.Fprintln(out, "#include QMK_KEYBOARD_H")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "enum layers {")
+	fmt.Fprintln(out, "    BASE,")
+	if levels.symbols != nil {
+		fmt.Fprintln(out, "    SYMBOLS,")
+	}
+	fmt.Fprintln(out, "};")
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, "const uint16_t PROGMEM keymaps[][MATRIX_ROWS][MATRIX_COLS] = {")
+	keys := make([]string, len(levels.base))
+	for i, r := range levels.base {
+		keys[i] = qmkKeycode(r)
+	}
+	qmkLayout(out, "BASE", keys, [3][2]string{{"KC_TAB", "KC_BSPC"}, {"KC_LCTL", ""}, {"KC_LSFT", "KC_RSFT"}}, thumbs)
+
+	if levels.symbols != nil {
+		for i, r := range levels.symbols {
+			keys[i] = qmkKeycode(r)
+		}
+		var symbolThumbs [6]string
+		for i := range symbolThumbs {
+			symbolThumbs[i] = "_______"
+		}
+		trans := [2]string{"_______", "_______"}
+		qmkLayout(out, "SYMBOLS", keys, [3][2]string{trans, trans, trans}, symbolThumbs)
+	}
+	fmt.Fprintln(out, "};")
+
+	if _, ok := kb.GroupId[kbd.MagicKey]; ok && len(kb.Magic) > 0 {
+		prevs := make([]rune, 0, len(kb.Magic))
+		for prev := range kb.Magic {
+			prevs = append(prevs, prev)
+		}
+		slices.Sort(prevs)
+
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "// Keys typed by the magic key after each key")
+		fmt.Fprintln(out, "uint16_t get_alt_repeat_key_keycode_user(uint16_t keycode, uint8_t mods) {")
+		fmt.Fprintln(out, "    switch (keycode) {")
+		for _, prev := range prevs {
+			fmt.Fprintf(out, "        case %s: return %s;\n", qmkKeycode(prev), qmkKeycode(kb.Magic[prev]))
+		}
+		fmt.Fprintln(out, "    }")
+		fmt.Fprintln(out, "    return KC_TRNS;")
+		fmt.Fprintln(out, "}")
+	}
+	return out.Flush()
+}
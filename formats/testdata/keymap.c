@@ -0,0 +1,33 @@
+// QMK keymap of Test & Co for a split 3x6 keyboard with 3 thumb keys per hand
+// The adaptive keys need REPEAT_KEY_ENABLE = yes in rules.mk
+// Characters outside of ASCII need UNICODE_ENABLE = yes in rules.mk
+#include QMK_KEYBOARD_H
+
+enum layers {
+    BASE,
+    SYMBOLS,
+};
+
+const uint16_t PROGMEM keymaps[][MATRIX_ROWS][MATRIX_COLS] = {
+    [BASE] = LAYOUT_split_3x6_3(
+        KC_TAB,  KC_Q,    KC_W,    KC_E,    KC_R,    KC_T,    KC_Y,    KC_U,    KC_I,    KC_O,    KC_P,    KC_BSPC,
+        KC_LCTL, KC_A,    KC_S,    KC_D,    KC_F,    KC_G,    KC_H,    KC_J,    KC_K,    KC_L,    UC(0x00F6), KC_QUOT,
+        KC_LSFT, KC_Z,    KC_X,    KC_C,    KC_V,    KC_B,    KC_N,    KC_M,    KC_COMM, KC_DOT,  QK_AREP, KC_RSFT,
+                                   KC_LGUI, KC_ESC,  KC_SPC,  QK_REP,  MO(SYMBOLS), KC_RALT
+    ),
+    [SYMBOLS] = LAYOUT_split_3x6_3(
+        _______, KC_EXLM, KC_AT,   KC_HASH, KC_DLR,  KC_PERC, KC_CIRC, KC_AMPR, KC_ASTR, KC_LPRN, KC_RPRN, _______,
+        _______, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX, XXXXXXX,
+        _______, KC_MINS, KC_EQL,  KC_PLUS, KC_UNDS, KC_LCBR, KC_RCBR, KC_LABK, KC_RABK, KC_LBRC, KC_RBRC, _______,
+                                   _______, _______, _______, _______, _______, _______
+    ),
+};
+
+// Keys typed by the magic key after each key
+uint16_t get_alt_repeat_key_keycode_user(uint16_t keycode, uint8_t mods) {
+    switch (keycode) {
+        case KC_E: return KC_A;
+        case KC_O: return KC_U;
+    }
+    return KC_TRNS;
+}
@@ -0,0 +1,110 @@
+package formats
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Genkey layout files have the name on the first line, three rows of keys and a finger map of
+// the same shape, where 0 to 3 are the left fingers from the pinky and 6 to 9 the right fingers
+// from the index. Oxeylyzer .kb files only have the rows of keys, with a gap between the hands.
+// Both tools use 30 keys, so the 11th key of the home row is written only if it is not the
+// apostrophe, which it becomes again when reading a file without it.
+
+func ImportGenkey(r io.Reader) (Layout, error) {
+	return importText(r, true)
+}
+
+func ImportOxeylyzer(r io.Reader) (Layout, error) {
+	return importText(r, false)
+}
+
+func importText(r io.Reader, named bool) (Layout, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Layout{}, err
+	}
+
+	var l Layout
+	if named {
+		if len(lines) == 0 {
+			return Layout{}, fmt.Errorf("layout file is empty")
+		}
+		l.Name = lines[0]
+		lines = lines[1:]
+	}
+	// anything after the keys, such as the finger map, is ignored
+	if len(lines) < len(rowLengths) {
+		return Layout{}, fmt.Errorf("layout needs %d rows, got %d", len(rowLengths), len(lines))
+	}
+
+	keys := [][]rune{}
+	for i, line := range lines[:len(rowLengths)] {
+		row, err := parseRow(line)
+		if err != nil {
+			return Layout{}, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		keys = append(keys, row)
+	}
+
+	var err error
+	l.Keyboard, err = joinRows(keys)
+	return l, err
+}
+
+func ExportGenkey(w io.Writer, l Layout) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, cmp.Or(l.Name, defaultName))
+
+	keys := textRows(l)
+	for _, row := range keys {
+		fmt.Fprintln(out, joinKeys(row, " "))
+	}
+	for _, row := range keys {
+		fingers := make([]string, len(row))
+		for col := range row {
+			finger := columnFinger(col)
+			// genkey numbers the thumbs 4 and 5, before the right fingers
+			if finger >= 4 {
+				finger += 2
+			}
+			fingers[col] = fmt.Sprint(finger)
+		}
+		fmt.Fprintln(out, strings.Join(fingers, " "))
+	}
+	return out.Flush()
+}
+
+func ExportOxeylyzer(w io.Writer, l Layout) error {
+	out := bufio.NewWriter(w)
+	for _, row := range textRows(l) {
+		fmt.Fprintln(out, joinKeys(row[:5], " ")+"  "+joinKeys(row[5:], " "))
+	}
+	return out.Flush()
+}
+
+// Rows of the layout without the 11th key of the home row if it is the apostrophe
+func textRows(l Layout) [][]rune {
+	keys := rows(l.Keyboard.Layout)
+	if home := keys[1]; home[len(home)-1] == '\'' {
+		keys[1] = home[:len(home)-1]
+	}
+	return keys
+}
+
+func joinKeys(keys []rune, sep string) string {
+	s := make([]string, len(keys))
+	for i, r := range keys {
+		s[i] = string(r)
+	}
+	return strings.Join(s, sep)
+}
@@ -0,0 +1,165 @@
+package formats
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	kbd "kbannealing/keyboard"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// xkb symbols files map key codes to keysyms. Keys of the layout are <AD01> to <AD10> on the
+// top row, <AC01> to <AC11> on the home row and <AB01> to <AB10> on the bottom row, other keys
+// come from the included us layout.
+
+// Names of the keysyms of ASCII symbols, other characters use their Unicode keysym, ex. U00E4
+var keysyms = map[rune]string{
+	' ': "space", '!': "exclam", '"': "quotedbl", '#': "numbersign", '$': "dollar", '%': "percent",
+	'&': "ampersand", '\'': "apostrophe", '(': "parenleft", ')': "parenright", '*': "asterisk",
+	'+': "plus", ',': "comma", '-': "minus", '.': "period", '/': "slash", ':': "colon",
+	';': "semicolon", '<': "less", '=': "equal", '>': "greater", '?': "question", '@': "at",
+	'[': "bracketleft", '\\': "backslash", ']': "bracketright", '^': "asciicircum",
+	'_': "underscore", '`': "grave", '{': "braceleft", '|': "bar", '}': "braceright",
+	'~': "asciitilde",
+}
+
+var keysymRunes = map[string]rune{}
+
+func init() {
+	for r, name := range keysyms {
+		keysymRunes[name] = r
+	}
+}
+
+// Key code of each row of a layout in row form
+var xkbRows = []string{"AD", "AC", "AB"}
+
+// Returns the keysym typing r, NoSymbol for empty keys and adaptive keys
+func keysym(r rune) string {
+	switch {
+	case r == kbd.Blank || r == kbd.RepeatKey || r == kbd.MagicKey:
+		return "NoSymbol"
+	case keysyms[r] != "":
+		return keysyms[r]
+	case r < 0x80:
+		return string(r)
+	default:
+		return fmt.Sprintf("U%04X", r)
+	}
+}
+
+// Returns the character of a keysym, Blank for NoSymbol
+func keysymRune(sym string) (rune, error) {
+	if sym == "NoSymbol" {
+		return kbd.Blank, nil
+	}
+	if r, ok := keysymRunes[sym]; ok {
+		return r, nil
+	}
+	if utf8.RuneCountInString(sym) == 1 {
+		r, _ := utf8.DecodeRuneInString(sym)
+		return r, nil
+	}
+	if strings.HasPrefix(sym, "U") {
+		if code, err := strconv.ParseUint(sym[1:], 16, 32); err == nil {
+			return rune(code), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported keysym %s", sym)
+}
+
+var (
+	xkbName = regexp.MustCompile(`xkb_symbols\s+"([^"]*)"`)
+	xkbKey  = regexp.MustCompile(`key\s*<(A[DCB])(\d\d)>\s*\{([^}]*)\}`)
+	// group qualifiers such as type[Group1] and symbols[Group1], before the list of keysyms
+	xkbGroup = regexp.MustCompile(`\w+\s*\[\s*Group\d+\s*\]`)
+	xkbLevel = regexp.MustCompile(`\[\s*([^,\]\s]+)`)
+)
+
+// Reads the first xkb_symbols section of a symbols file, using the first level of every key
+func ImportXKB(r io.Reader) (Layout, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Layout{}, err
+	}
+	text := string(data)
+
+	var l Layout
+	if m := xkbName.FindStringSubmatchIndex(text); m != nil {
+		l.Name = text[m[2]:m[3]]
+		// the section ends at the next one
+		if next := xkbName.FindStringIndex(text[m[1]:]); next != nil {
+			text = text[:m[1]+next[0]]
+		}
+	}
+
+	keys := make([][]rune, len(xkbRows))
+	for _, m := range xkbKey.FindAllStringSubmatch(text, -1) {
+		row := slices.Index(xkbRows, m[1])
+		col, _ := strconv.Atoi(m[2])
+		if col < 1 || col > rowLengths[row] {
+			continue
+		}
+		level := xkbLevel.FindStringSubmatch(xkbGroup.ReplaceAllString(m[3], ""))
+		if level == nil {
+			return Layout{}, fmt.Errorf("key <%s%s> has no keysyms", m[1], m[2])
+		}
+		key, err := keysymRune(level[1])
+		if err != nil {
+			return Layout{}, fmt.Errorf("key <%s%s>: %w", m[1], m[2], err)
+		}
+		for len(keys[row]) < col {
+			keys[row] = append(keys[row], 0)
+		}
+		keys[row][col-1] = key
+	}
+
+	for i, row := range keys {
+		for col, key := range row {
+			if key == 0 {
+				return Layout{}, fmt.Errorf("key <%s%02d> is missing", xkbRows[i], col+1)
+			}
+		}
+	}
+
+	l.Keyboard, err = joinRows(keys)
+	return l, err
+}
+
+// Writes an xkb_symbols section on top of the us layout. The symbol layer is typed with AltGr.
+func ExportXKB(w io.Writer, l Layout) error {
+	out := bufio.NewWriter(w)
+	name := cmp.Or(l.Name, defaultName)
+	fmt.Fprintln(out, "default partial alphanumeric_keys")
+	fmt.Fprintf(out, "xkb_symbols %q {\n", name)
+	fmt.Fprintf(out, "    include \"us(basic)\"\n")
+	levels := keyLevels(l.Keyboard)
+	if levels.symbols != nil {
+		fmt.Fprintf(out, "    include \"level3(ralt_switch)\"\n")
+	}
+	fmt.Fprintf(out, "    name[Group1] = %q;\n\n", name)
+
+	i := 0
+	for row, keys := range rows(l.Keyboard.Layout) {
+		for col := range keys {
+			syms := keysym(levels.base[i])
+			if levels.symbols != nil {
+				syms = fmt.Sprintf("type[Group1] = \"FOUR_LEVEL\", [ %s, %s, %s, NoSymbol ]",
+					syms, keysym(levels.shifted[i]), keysym(levels.symbols[i]))
+			} else if levels.shifted[i] != kbd.Blank {
+				syms = fmt.Sprintf("[ %s, %s ]", syms, keysym(levels.shifted[i]))
+			} else {
+				syms = fmt.Sprintf("[ %s ]", syms)
+			}
+			fmt.Fprintf(out, "    key <%s%02d> { %s };\n", xkbRows[row], col+1, syms)
+			i++
+		}
+	}
+	fmt.Fprintln(out, "};")
+	return out.Flush()
+}
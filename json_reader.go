@@ -1,20 +1,86 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
-type LayoutMap map[string]string
+// Version of the layouts file format written by saveLayoutToJSON
+const layoutsVersion = 2
 
+// A layout in row form and where it came from. Everything but the layout is optional.
+type LayoutEntry struct {
+	Layout string `json:"layout"`
+	// Thumb keys from left to right, Blank for a thumb key without a character
+	Thumbs string `json:"thumbs,omitempty"`
+	// Keys of the layers that are not shifted in row form, by the name of the layer
+	Layers map[string]string `json:"layers,omitempty"`
+	// Magic key rules of the previous and typed character, ex. "ea,ou"
+	Magic       string `json:"magic,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Geometry and finger map the layout was made for, only the row staggered keyboard with
+	// the standard finger map exists so far
+	Geometry  string `json:"geometry,omitempty"`
+	FingerMap string `json:"fingermap,omitempty"`
+	// Keys that were locked in place while the layout was optimized
+	Locked string `json:"locked,omitempty"`
+	// Creation time in RFC 3339 form
+	Created string `json:"created,omitempty"`
+	// Frequency data the layout was optimized for, and its stats on that data at the time
+	Corpus string             `json:"corpus,omitempty"`
+	Scores map[string]float64 `json:"scores,omitempty"`
+}
+
+// Returns the letters of alphabet that are not on the thumb keys or layers of the entry, which
+// the base layout needs to have
+func (e LayoutEntry) offLayout(alphabet string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(e.Thumbs, r) {
+			return -1
+		}
+		for _, keys := range e.Layers {
+			if strings.ContainsRune(keys, r) {
+				return -1
+			}
+		}
+		return r
+	}, alphabet)
+}
+
+type LayoutMap map[string]LayoutEntry
+
+// The layouts file, older files are a flat map of name to layout
+type layoutsFile struct {
+	Version int       `json:"version"`
+	Layouts LayoutMap `json:"layouts"`
+}
+
+// Writes a layouts file, keeping the layouts it replaces in filename.bak
 func saveLayoutToJSON(filename string, layouts LayoutMap) error {
-	data, err := json.MarshalIndent(layouts, "", "  ")
-	if err != nil {
+	// <, > and & are left as they are, as they are common on symbol layers
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(layoutsFile{layoutsVersion, layouts}); err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0644)
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if err := backup(filename); err != nil {
+		return fmt.Errorf("could not back up %s: %w", filename, err)
+	}
+	return writeFileAtomic(filename, data)
 }
 
+// Reads a layouts file, either with metadata or as a flat map of name to layout.
 // layouts are in row form, but will be converted to column form
 func loadLayoutFromJSON(filename string) (LayoutMap, error) {
 	data, err := os.ReadFile(filename)
@@ -22,15 +88,61 @@ func loadLayoutFromJSON(filename string) (LayoutMap, error) {
 		return nil, err
 	}
 
-	var layouts LayoutMap
-	err = json.Unmarshal(data, &layouts)
-	if err != nil {
-		return nil, err
+	// files with metadata have a version, a flat map may only have a layout named version
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if json.Unmarshal(data, &probe) == nil && probe.Version > 0 {
+		var file layoutsFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, positioned(data, err))
+		}
+		if file.Version > layoutsVersion {
+			return nil, fmt.Errorf("%s is a version %d layouts file, newer than version %d", filename, file.Version, layoutsVersion)
+		}
+		for name, entry := range file.Layouts {
+			if entry.Layout == "" {
+				return nil, fmt.Errorf("layout %s in %s has no layout", name, filename)
+			}
+		}
+		if file.Layouts == nil {
+			file.Layouts = LayoutMap{}
+		}
+		return file.Layouts, nil
+	}
+
+	flat := map[string]string{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, positioned(data, err))
 	}
 
+	layouts := LayoutMap{}
+	for name, layout := range flat {
+		layouts[name] = LayoutEntry{Layout: layout}
+	}
 	return layouts, nil
 }
 
+// Adds the line and column to JSON syntax and type errors, which only have a byte offset
+func positioned(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	before := data[:min(int(offset), len(data))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("line %d column %d: %w", line, col, err)
+}
+
 type StatMap map[string]map[string]float64
 
 func saveStatsToJSON(filename string, stats StatMap) error {
@@ -38,5 +150,78 @@ func saveStatsToJSON(filename string, stats StatMap) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0644)
+	return writeFileAtomic(filename, data)
+}
+
+// Writes a temporary file next to filename and renames it over filename, so the file is either
+// fully written or left as it was, even if the program is interrupted. A replaced file keeps its
+// mode, new files are readable by everyone.
+func writeFileAtomic(filename string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Copies a file to filename.bak with its mode, if the file exists
+func backup(filename string) error {
+	info, err := os.Stat(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename+".bak", data); err != nil {
+		return err
+	}
+	return os.Chmod(filename+".bak", info.Mode().Perm())
+}
+
+// Inserts a timestamp before the extension of a file name, ex. layouts.json -> layouts-20240102-150405.json
+func timestamped(filename string, t time.Time) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "-" + t.Format("20060102-150405") + ext
+}
+
+// Returns name if it is not in layouts yet, otherwise name followed by the lowest free number from 2
+func uniqueName(layouts LayoutMap, name string) string {
+	if _, ok := layouts[name]; !ok {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s %d", name, i)
+		if _, ok := layouts[candidate]; !ok {
+			return candidate
+		}
+	}
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFlatLayouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layouts.json")
+	// a flat file may have a layout named version, which is not the version of the file
+	flat := `{"qwerty": "qwertyuiopasdfghjkl;'zxcvbnm,./", "version": "qwfpgjluy;arstdhneio'zxcvbkm,./"}`
+	if err := os.WriteFile(path, []byte(flat), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layouts, err := loadLayoutFromJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layouts) != 2 || layouts["qwerty"].Layout != "qwertyuiopasdfghjkl;'zxcvbnm,./" ||
+		layouts["version"].Layout != "qwfpgjluy;arstdhneio'zxcvbkm,./" {
+		t.Fatalf("loadLayoutFromJSON() of a flat file = %v", layouts)
+	}
+
+	// flat files are saved with metadata, and read back the same
+	if err := saveLayoutToJSON(path, layouts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version": 2`) {
+		t.Errorf("saveLayoutToJSON() = %s but want a version 2 file", data)
+	}
+	saved, err := loadLayoutFromJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, layouts) {
+		t.Errorf("loadLayoutFromJSON() of a saved flat file = %v but want %v", saved, layouts)
+	}
+}
+
+func TestLoadLayouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layouts.json")
+	layouts := LayoutMap{
+		"thumbs": {
+			Layout: "qwfpgjluy;ar·tdhneio'zxcvbkm,./",
+			Thumbs: "s ",
+			Layers: map[string]string{"symbols": "!@#$%^&*()[]{}<>-_=+\\|`~·····;:"},
+			Magic:  "ea,ou",
+			Author: "someone",
+			Scores: map[string]float64{"sfb": 1.5},
+		},
+	}
+	if err := saveLayoutToJSON(path, layouts); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadLayoutFromJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, layouts) {
+		t.Errorf("loadLayoutFromJSON() = %v but want %v", saved, layouts)
+	}
+
+	tests := map[string]string{
+		"newer version":  `{"version": 3, "layouts": {}}`,
+		"no layout":      `{"version": 2, "layouts": {"empty": {"author": "someone"}}}`,
+		"a syntax error": "{\n  \"qwerty\": qwerty\n}",
+	}
+	for name, data := range tests {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadLayoutFromJSON(path)
+		if err == nil {
+			t.Errorf("loadLayoutFromJSON() of %s should fail", name)
+		} else if name == "a syntax error" && !strings.Contains(err.Error(), "line 2 ") {
+			t.Errorf("loadLayoutFromJSON() = %s but want the line of the syntax error", err)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "layouts.json")
+
+	if err := writeFileAtomic(path, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("writeFileAtomic() of a new file = %v, %v but want mode 0644", info.Mode(), err)
+	}
+
+	// a replaced file keeps its mode
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Errorf("writeFileAtomic() wrote %q, %v but want %q", data, err, "second")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("writeFileAtomic() of a 0600 file = %v, %v but want mode 0600", info.Mode(), err)
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("writeFileAtomic() left %v, %v", entries, err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "layouts.json"), nil); err == nil {
+		t.Error("writeFileAtomic() into a missing folder should fail")
+	}
+}
+
+func TestSaveLayoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layouts.json")
+
+	if err := saveLayoutToJSON(path, LayoutMap{"qwerty": {Layout: "qwertyuiopasdfghjkl;'zxcvbnm,./"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".bak"); err == nil {
+		t.Error("saveLayoutToJSON() backed up a file that did not exist")
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveLayoutToJSON(path, LayoutMap{"colemak": {Layout: "qwfpgjluy;arstdhneio'zxcvbkm,./"}}); err != nil {
+		t.Fatal(err)
+	}
+	layouts, err := loadLayoutFromJSON(path + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := layouts["qwerty"]; !ok || len(layouts) != 1 {
+		t.Errorf("backup of saveLayoutToJSON() = %v but want the replaced qwerty", layouts)
+	}
+	if info, err := os.Stat(path + ".bak"); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("backup of saveLayoutToJSON() = %v, %v but want mode 0600", info.Mode(), err)
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	layouts := LayoutMap{"000 optimized sfb": {}, "000 optimized sfb 2": {}}
+
+	tests := map[string]string{
+		"000 optimized roll": "000 optimized roll",
+		"000 optimized sfb":  "000 optimized sfb 3",
+	}
+	for name, want := range tests {
+		if got := uniqueName(layouts, name); got != want {
+			t.Errorf("uniqueName(%q) = %q but want %q", name, got, want)
+		}
+	}
+}
+
+func TestTimestamped(t *testing.T) {
+	at := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := map[string]string{
+		"layouts.json":     "layouts-20240102-150405.json",
+		"out/results.json": "out/results-20240102-150405.json",
+		"stats":            "stats-20240102-150405",
+	}
+	for filename, want := range tests {
+		if got := timestamped(filename, at); got != want {
+			t.Errorf("timestamped(%q) = %q but want %q", filename, got, want)
+		}
+	}
+}
+
+func TestOffLayout(t *testing.T) {
+	entry := LayoutEntry{Thumbs: "s ", Layers: map[string]string{"umlauts": "äöü"}}
+	if got := entry.offLayout("abcsäöüß"); got != "abcß" {
+		t.Errorf("offLayout() = %q but want %q", got, "abcß")
+	}
+}
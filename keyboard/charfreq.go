@@ -3,21 +3,37 @@ package keyboard
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type CharFreq struct {
 	Chars    map[rune]int
 	Bigrams  map[string]int
 	Trigrams map[string]int
+	// Quadgrams are optional, empty unless they were extracted or read from quadgrams.txt
+	Quadgrams map[string]int
 }
 
 func NewCharFreq(path string) (*CharFreq, error) {
+	return newCharFreq(path, false)
+}
+
+// Same as NewCharFreq, but quadgrams are extracted as well
+func NewCharFreqWithQuadgrams(path string) (*CharFreq, error) {
+	return newCharFreq(path, true)
+}
+
+func newCharFreq(path string, withQuadgrams bool) (*CharFreq, error) {
 	chars := make(map[rune]int)
 	bigrams := make(map[string]int)
 	trigrams := make(map[string]int)
+	quadgrams := make(map[string]int)
 
 	file, err := os.Open(path)
 	if err != nil {
@@ -26,21 +42,28 @@ func NewCharFreq(path string) (*CharFreq, error) {
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
+	// lines are only limited by memory
+	scanner.Buffer(nil, math.MaxInt)
 
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := []rune(scanner.Text())
 		if len(line) < 2 {
 			continue
 		}
 
 		for i := 0; i < len(line)-1; i++ {
-			bigram := line[i : i+2]
+			bigram := string(line[i : i+2])
 			bigrams[bigram]++
 
 			if i+3 <= len(line) {
-				trigram := line[i : i+3]
+				trigram := string(line[i : i+3])
 				trigrams[trigram]++
 			}
+
+			if withQuadgrams && i+4 <= len(line) {
+				quadgram := string(line[i : i+4])
+				quadgrams[quadgram]++
+			}
 		}
 
 		for _, r := range line {
@@ -49,17 +72,18 @@ func NewCharFreq(path string) (*CharFreq, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		fmt.Println("Error reading file:", err)
+		return nil, err
 	}
 
-	return &CharFreq{chars, bigrams, trigrams}, nil
+	return &CharFreq{chars, bigrams, trigrams, quadgrams}, nil
 }
 
 func CharFreqFromFolder(path string) (*CharFreq, error) {
 	cf := &CharFreq{
-		Chars:    make(map[rune]int),
-		Bigrams:  make(map[string]int),
-		Trigrams: make(map[string]int),
+		Chars:     make(map[rune]int),
+		Bigrams:   make(map[string]int),
+		Trigrams:  make(map[string]int),
+		Quadgrams: make(map[string]int),
 	}
 
 	readFile := func(filename string, m interface{}) error {
@@ -71,21 +95,24 @@ func CharFreqFromFolder(path string) (*CharFreq, error) {
 
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
-			parts := strings.Fields(scanner.Text())
-			if len(parts) != 2 {
+			// the count is after the last space, so keys may contain spaces
+			line := strings.TrimRight(scanner.Text(), " \t\r")
+			sep := strings.LastIndexAny(line, " \t")
+			if sep < 1 {
 				continue
 			}
 
-			key := parts[0]
-			value, err := strconv.Atoi(parts[1])
+			key := line[:sep]
+			value, err := strconv.Atoi(line[sep+1:])
 			if err != nil {
 				continue
 			}
 
 			switch v := m.(type) {
 			case map[rune]int:
-				if len(key) == 1 {
-					v[rune(key[0])] = value
+				if utf8.RuneCountInString(key) == 1 {
+					r, _ := utf8.DecodeRuneInString(key)
+					v[r] = value
 				}
 			case map[string]int:
 				v[key] = value
@@ -105,5 +132,58 @@ func CharFreqFromFolder(path string) (*CharFreq, error) {
 		return nil, err
 	}
 
+	// quadgrams are optional
+	if err := readFile(path+"/quadgrams.txt", cf.Quadgrams); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
 	return cf, nil
 }
+
+// Writes the n-grams of cf into monograms.txt, bigrams.txt, trigrams.txt and quadgrams.txt (if cf has
+// quadgrams) in a folder, sorted from most to least frequent. The folder can be read back with CharFreqFromFolder.
+func (cf *CharFreq) SaveToFolder(path string) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	chars := make(map[string]int, len(cf.Chars))
+	for c, val := range cf.Chars {
+		chars[string(c)] = val
+	}
+
+	if err := writeNgrams(filepath.Join(path, "monograms.txt"), chars); err != nil {
+		return err
+	}
+	if err := writeNgrams(filepath.Join(path, "bigrams.txt"), cf.Bigrams); err != nil {
+		return err
+	}
+	if err := writeNgrams(filepath.Join(path, "trigrams.txt"), cf.Trigrams); err != nil {
+		return err
+	}
+	if len(cf.Quadgrams) > 0 {
+		return writeNgrams(filepath.Join(path, "quadgrams.txt"), cf.Quadgrams)
+	}
+	return nil
+}
+
+func writeNgrams(filename string, ngrams map[string]int) error {
+	keys := make([]string, 0, len(ngrams))
+	for key := range ngrams {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b string) int {
+		if ngrams[a] != ngrams[b] {
+			return ngrams[b] - ngrams[a]
+		}
+		return strings.Compare(a, b)
+	})
+
+	var sb strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "%s %d\n", key, ngrams[key])
+	}
+
+	return os.WriteFile(filename, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,78 @@
+package keyboard
+
+// Position of a key in key widths, x to the right and y down from the top left key
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Horizontal offset of each row of a row staggered keyboard
+var rowStagger = []float64{0, 0.25, 0.75}
+
+// Positions of the thumb keys, below the bottom row
+var thumbPositions = []Point{{3.75, 3}, {6.25, 3}}
+
+// Positions of the shift keys, on each side of the bottom row
+var shiftPositions = map[rune]Point{
+	LeftShift:  {-0.5, 2},
+	RightShift: {10.75, 2},
+}
+
+// Index in the row layout of the home key of each finger, the thumbs rest on the thumb keys
+var homeIndexes = []int{10, 11, 12, 13, 16, 17, 18, 19}
+
+// Returns the row and column of an index in a row layout
+func RowCol(index int) (int, int) {
+	switch {
+	case index < 10:
+		return 0, index
+	case index < 21:
+		return 1, index - 10
+	default:
+		return 2, index - 21
+	}
+}
+
+// Position of the key at an index in a row layout
+func KeyPosition(index int) Point {
+	row, col := RowCol(index)
+	return Point{float64(col) + rowStagger[row], float64(row)}
+}
+
+// Position of a thumb key, 0 for the left thumb and 1 for the right thumb
+func ThumbPosition(thumb int) Point {
+	return thumbPositions[thumb]
+}
+
+// Position where a finger rests
+func HomePosition(finger int) Point {
+	if finger >= LeftThumb {
+		return ThumbPosition(finger - LeftThumb)
+	}
+	return KeyPosition(homeIndexes[finger])
+}
+
+// Returns the position of the key typing r. Layer activators without a position of their own,
+// other than the shift keys, are pressed at the home position of their finger.
+func (k *Keyboard) Position(r rune) (Point, bool) {
+	for i, c := range []rune(k.Layout) {
+		if c == r {
+			return KeyPosition(i), true
+		}
+	}
+
+	for i, c := range []rune(k.Thumbs) {
+		if c == r && i < len(thumbPositions) {
+			return ThumbPosition(i), true
+		}
+	}
+
+	if pos, ok := shiftPositions[r]; ok {
+		return pos, true
+	}
+	if finger, ok := k.GroupId[r]; ok {
+		return HomePosition(finger), true
+	}
+
+	return Point{}, false
+}
@@ -7,66 +7,110 @@ import (
 	"slices"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Keyboard struct {
-	Layout    string
-	Left      string
-	Right     string
-	GroupId   map[rune]int
+	Layout  string
+	Left    string
+	Right   string
+	GroupId map[rune]int
+	// Keys typed by each finger: the keys of the layout, followed by any layer activation keys.
+	// Keyboards with thumb keys have two more groups for the left and right thumb.
 	Groups    []string
 	colLayout string
+	// Layers on top of the base layout, see layers.go
+	Layers []Layer
+	// Left and right thumb keys, see thumbs.go
+	Thumbs string
+	// Rules of the magic key, see magic.go
+	Magic   MagicRules
+	strokes *strokeCache
 }
 
 // layout is a string of 31 characters representing a keyboard row by row
 // e.g. The standard qwerty layout is: "qwertyuiopasdfghjkl;'zxcvbnm,./"
+// Characters are counted as runes, so layouts may contain letters such as ä, ø, ß or Cyrillic.
+// Panics if the layout is invalid, use ParseKeyboard for layouts that are not known to be valid.
 func NewKeyboard(layout string) *Keyboard {
-	if len(layout) != 31 {
-		panicMsg := fmt.Sprint("Invalid layout length ", layout, " length is ", len(layout))
+	kb, err := ParseKeyboard(layout)
+	if err != nil {
+		panic(err)
+	}
+	return kb
+}
+
+func newKeyboard(layout string, thumbs string, layers []Layer, magic MagicRules) *Keyboard {
+	if utf8.RuneCountInString(layout) != 31 {
+		panicMsg := fmt.Sprint("Invalid layout length ", layout, " length is ", utf8.RuneCountInString(layout))
 		panic(panicMsg)
 	}
 
 	colLayout := RowLayoutToCol(layout)
 	groups := ColLayoutToGroups(colLayout)
+	layers = buildLayers(layout, layers)
+	acts := activators(layers)
+
+	if thumbs != "" || slices.ContainsFunc(acts, func(a Activator) bool { return a.Finger >= 8 }) {
+		groups = append(groups, thumbGroups(thumbs)...)
+	}
+
+	for _, act := range acts {
+		groups[act.Finger] += string(act.Key)
+	}
+
 	groupId := map[rune]int{}
+	left := ""
+	right := ""
 
 	for i, group := range groups {
 		for _, c := range group {
 			groupId[c] = i
 		}
+
+		if FingerOnLeft(i) {
+			left += group
+		} else {
+			right += group
+		}
 	}
 
-	left := colLayout[0:15]
-	right := colLayout[15:31]
+	return &Keyboard{layout, left, right, groupId, groups, colLayout, layers, thumbs, magic, &strokeCache{}}
+}
 
-	return &Keyboard{layout, left, right, groupId, groups, colLayout}
+// Creates a keyboard with the same layers, thumb keys and magic rules and a different base layout
+func (k *Keyboard) WithLayout(layout string) *Keyboard {
+	return newKeyboard(layout, k.Thumbs, k.Layers, k.Magic)
 }
 
 func (k *Keyboard) GetGroup(r rune) int {
 	return k.GroupId[r]
 }
 
+// Groups [0, 4) and the left thumb are typed by the left hand
 func (k *Keyboard) OnLeft(r rune) bool {
-	idx := strings.Index(k.colLayout, string(r))
-	if idx == -1 {
+	group, ok := k.GroupId[r]
+	if !ok {
 		return false
 	}
-	return idx < 15
+	return FingerOnLeft(group)
 }
 
+// Groups [4, 8) and the right thumb are typed by the right hand
 func (k *Keyboard) OnRight(r rune) bool {
-	idx := strings.Index(k.colLayout, string(r))
-	if idx == -1 {
+	group, ok := k.GroupId[r]
+	if !ok {
 		return false
 	}
-	return idx >= 15
+	return !FingerOnLeft(group)
 }
 
 // Returns a formatted string in the shape of a keyboard. To get a one-line string, that is stored in k.Layout
 func (k *Keyboard) GetKeyboardString() string {
-	row1 := k.Layout[0:10]
-	row2 := k.Layout[10:21]
-	row3 := k.Layout[21:31]
+	layout := []rune(k.Layout)
+	row1 := string(layout[0:10])
+	row2 := string(layout[10:21])
+	row3 := string(layout[21:31])
 
 	tmp := strings.Split(row1, "")
 	row1 = strings.Join(tmp, "  ")
@@ -77,7 +121,23 @@ func (k *Keyboard) GetKeyboardString() string {
 	tmp = strings.Split(row3, "")
 	row3 = strings.Join(tmp, "  ")
 
-	return row1 + "\n" + row2 + "\n" + row3
+	out := row1 + "\n" + row2 + "\n" + row3
+	if k.Thumbs != "" {
+		out += "\n" + k.thumbString()
+	}
+	if len(k.Magic) > 0 && k.HasMagic() {
+		out += "\nmagic: " + k.Magic.String()
+	}
+
+	// shifted layers follow the base layout, so only the other layers are shown
+	for _, layer := range k.Layers {
+		if layer.Shifted {
+			continue
+		}
+		out += "\n[" + layer.Name + "]\n" + newKeyboard(layer.Keys, "", nil, nil).GetKeyboardString()
+	}
+
+	return out
 }
 
 func (k *Keyboard) PrintKeyboard() {
@@ -85,11 +145,32 @@ func (k *Keyboard) PrintKeyboard() {
 }
 
 // Creates a derivative keyboard by swappinng random characters in the layout.
-// Locked indexes will not be swapped. Index refers to the position of a character in k.Layout
+// Locked indexes will not be swapped. Index refers to the position of a character in k.Layout,
+// followed by the positions of each layer that is not shifted, 31 per layer, and then the thumb keys.
+// Symbols can be swapped between the base layout and layers, and letters between the base layout
+// and thumb keys, see canSwap.
 func MutateKeyboard(k *Keyboard, swaps int, lockedIndexes []int) *Keyboard {
 	chars := []rune(k.Layout)
-	unlocked := []int{}
+	for _, layer := range k.Layers {
+		if !layer.Shifted {
+			chars = append(chars, []rune(layer.Keys)...)
+		}
+	}
+	thumbStart := len(chars)
+	chars = append(chars, []rune(k.Thumbs)...)
 
+	// 0 is the base layout, 1 the thumb keys and 2+ the layers
+	region := func(idx int) int {
+		if idx >= thumbStart {
+			return 1
+		}
+		if idx >= 31 {
+			return 1 + idx/31
+		}
+		return 0
+	}
+
+	unlocked := []int{}
 	for i := 0; i < len(chars); i++ {
 		if !utils.Contains(lockedIndexes, i) {
 			unlocked = append(unlocked, i)
@@ -97,11 +178,45 @@ func MutateKeyboard(k *Keyboard, swaps int, lockedIndexes []int) *Keyboard {
 	}
 
 	for i := 0; i < swaps; i++ {
-		a := rand.Intn(len(unlocked))
-		b := rand.Intn(len(unlocked))
-		chars[unlocked[a]], chars[unlocked[b]] = chars[unlocked[b]], chars[unlocked[a]]
+		a := unlocked[rand.Intn(len(unlocked))]
+		b := unlocked[rand.Intn(len(unlocked))]
+		if !canSwap(region(a), region(b), chars[a], chars[b]) {
+			continue
+		}
+		chars[a], chars[b] = chars[b], chars[a]
+	}
+
+	layers := slices.Clone(k.Layers)
+	offset := 31
+	for i := range layers {
+		if !layers[i].Shifted {
+			layers[i].Keys = string(chars[offset : offset+31])
+			offset += 31
+		}
+	}
+
+	return newKeyboard(string(chars[:31]), string(chars[thumbStart:]), layers, k.Magic)
+}
+
+// Keys can always be swapped within a region. Symbols can move between the base layout (region 0)
+// and layers (region 2+), and letters between the base layout and thumb keys (region 1), where a
+// blank thumb key leaves an empty key on the base layout. Space never leaves the thumbs.
+func canSwap(regionA int, regionB int, a rune, b rune) bool {
+	if regionA == regionB {
+		return true
+	}
+	if regionA > regionB {
+		regionA, regionB = regionB, regionA
+		a, b = b, a
+	}
+
+	switch {
+	case regionA == 0 && regionB >= 2:
+		return movableSymbol(a) && movableSymbol(b)
+	case regionA == 0 && regionB == 1:
+		return (unicode.IsLetter(a) || a == Blank) && (unicode.IsLetter(b) || b == Blank) && a != b
 	}
-	return NewKeyboard(string(chars))
+	return false
 }
 
 func OptimizeHomerow(k *Keyboard, cf *CharFreq, lockSymbols bool, lockColumns bool) *Keyboard {
@@ -116,10 +231,18 @@ func OptimizeHomerow(k *Keyboard, cf *CharFreq, lockSymbols bool, lockColumns bo
 		{3, 1, 2, 4},
 	}
 
-	newGroups := make([]string, len(k.Groups))
+	groups := ColLayoutToGroups(k.colLayout)
+	newGroups := make([]string, len(groups))
+
+	// keys on layers are bound to their column, so the columns can't be swapped
+	for _, layer := range k.Layers {
+		if !layer.Shifted {
+			lockColumns = true
+		}
+	}
 
 	for i, priority := range priorities {
-		chars := []rune(k.Groups[i])
+		chars := []rune(groups[i])
 		unlocked := make([]rune, 0, len(chars))
 		unlockedIdx := make([]int, 0, len(chars))
 		unlockedPriority := make([]int, 0, len(chars))
@@ -160,44 +283,47 @@ func OptimizeHomerow(k *Keyboard, cf *CharFreq, lockSymbols bool, lockColumns bo
 	// as long as we aren't optimizing for 3 rolls, we can do this.
 	if !lockColumns {
 		slices.SortFunc(newGroups[0:3], func(a, b string) int {
-			return cf.Chars[rune(a[1])] - cf.Chars[rune(b[1])]
+			return cf.Chars[[]rune(a)[1]] - cf.Chars[[]rune(b)[1]]
 		})
 
 		slices.SortFunc(newGroups[5:7], func(a, b string) int {
-			return cf.Chars[rune(b[1])] - cf.Chars[rune(a[1])]
+			return cf.Chars[[]rune(b)[1]] - cf.Chars[[]rune(a)[1]]
 		})
 	}
 
-	return NewKeyboard(GroupsToRow(newGroups))
+	return k.WithLayout(GroupsToRow(newGroups))
 }
 
 func ColLayoutToRow(colLayout string) string {
+	col := []rune(colLayout)
+
 	// except for the last character
-	columns := make([]string, 0, 10)
+	columns := make([][]rune, 0, 10)
 	for i := 0; i < 30; i += 3 {
-		columns = append(columns, colLayout[i:i+3])
+		columns = append(columns, col[i:i+3])
 	}
 
-	row1 := make([]byte, 0, 10)
-	row2 := make([]byte, 0, 11)
-	row3 := make([]byte, 0, 10)
+	row1 := make([]rune, 0, 10)
+	row2 := make([]rune, 0, 11)
+	row3 := make([]rune, 0, 10)
 
 	for i := 0; i < 10; i++ {
 		row1 = append(row1, columns[i][0])
 		row2 = append(row2, columns[i][1])
 		row3 = append(row3, columns[i][2])
 	}
-	row2 = append(row2, colLayout[len(colLayout)-1])
+	row2 = append(row2, col[len(col)-1])
 
 	return string(row1) + string(row2) + string(row3)
 }
 
 func RowLayoutToCol(rowLayout string) string {
-	row1 := rowLayout[0:10]
-	row2 := rowLayout[10:21]
-	row3 := rowLayout[21:31]
+	row := []rune(rowLayout)
+	row1 := row[0:10]
+	row2 := row[10:21]
+	row3 := row[21:31]
 
-	columns := make([]byte, 31)
+	columns := make([]rune, 31)
 
 	rowPtr := 0
 	for i := 0; i < 28; i += 3 {
@@ -212,15 +338,16 @@ func RowLayoutToCol(rowLayout string) string {
 }
 
 func ColLayoutToGroups(colLayout string) []string {
+	col := []rune(colLayout)
 	return []string{
-		colLayout[0:3],
-		colLayout[3:6],
-		colLayout[6:9],
-		colLayout[9:15],
-		colLayout[15:21],
-		colLayout[21:24],
-		colLayout[24:27],
-		colLayout[27:31],
+		string(col[0:3]),
+		string(col[3:6]),
+		string(col[6:9]),
+		string(col[9:15]),
+		string(col[15:21]),
+		string(col[21:24]),
+		string(col[24:27]),
+		string(col[27:31]),
 	}
 }
 
@@ -1,7 +1,10 @@
 package keyboard
 
 import (
+	"slices"
+	"strings"
 	"testing"
+	"unicode"
 )
 
 func CompareSlices[T comparable](a, b []T) bool {
@@ -173,3 +176,140 @@ func TestGroupsToCol(t *testing.T) {
 		t.Errorf("GroupToCol() = %s but want %s", GroupsToCol(groups), col)
 	}
 }
+
+func TestUnicodeKeyboard(t *testing.T) {
+	row := "üßертyuiopäsdfghjklöøzxcvbnm,.я"
+	col := "üäzßsxеdcрfvтgbyhnujmik,ol.pöяø"
+
+	if RowLayoutToCol(row) != col {
+		t.Errorf("RowLayoutToCol() = %s but want %s", RowLayoutToCol(row), col)
+	}
+
+	if ColLayoutToRow(col) != row {
+		t.Errorf("ColLayoutToRow() = %s but want %s", ColLayoutToRow(col), row)
+	}
+
+	kb := NewKeyboard(row)
+
+	for _, r := range "üäzßsxеdcрfvтgb" {
+		if !kb.OnLeft(r) || kb.OnRight(r) {
+			t.Errorf("OnLeft(%s) = false but want true", string(r))
+		}
+	}
+
+	for _, r := range "yhnujmik,ol.pöяø" {
+		if !kb.OnRight(r) || kb.OnLeft(r) {
+			t.Errorf("OnRight(%s) = false but want true", string(r))
+		}
+	}
+
+	mutated := MutateKeyboard(kb, 10, []int{})
+	for _, r := range row {
+		if _, ok := mutated.GroupId[r]; !ok {
+			t.Errorf("MutateKeyboard() lost character %s", string(r))
+		}
+	}
+}
+
+func TestCharFreqSaveToFolder(t *testing.T) {
+	cf := &CharFreq{
+		Chars:     map[rune]int{'a': 3, ' ': 5, 'ø': 1},
+		Bigrams:   map[string]int{"a ": 2, " a": 2, "aø": 1},
+		Trigrams:  map[string]int{"a a": 2, "aøa": 1},
+		Quadgrams: map[string]int{"a aø": 1},
+	}
+
+	dir := t.TempDir()
+	if err := cf.SaveToFolder(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := CharFreqFromFolder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for c, val := range cf.Chars {
+		if loaded.Chars[c] != val {
+			t.Errorf("Chars[%q] = %d but want %d", c, loaded.Chars[c], val)
+		}
+	}
+	for key, val := range cf.Bigrams {
+		if loaded.Bigrams[key] != val {
+			t.Errorf("Bigrams[%q] = %d but want %d", key, loaded.Bigrams[key], val)
+		}
+	}
+	for key, val := range cf.Trigrams {
+		if loaded.Trigrams[key] != val {
+			t.Errorf("Trigrams[%q] = %d but want %d", key, loaded.Trigrams[key], val)
+		}
+	}
+	for key, val := range cf.Quadgrams {
+		if loaded.Quadgrams[key] != val {
+			t.Errorf("Quadgrams[%q] = %d but want %d", key, loaded.Quadgrams[key], val)
+		}
+	}
+}
+
+func TestCharFreqQuadgrams(t *testing.T) {
+	cf, err := NewCharFreqWithQuadgrams("../CharFreqData/google-10000-english-usa.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Quadgrams["tion"] == 0 {
+		t.Error("NewCharFreqWithQuadgrams() is missing \"tion\"")
+	}
+
+	cf, err = NewCharFreq("../CharFreqData/google-10000-english-usa.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cf.Quadgrams) != 0 {
+		t.Error("NewCharFreq() extracted quadgrams")
+	}
+
+	// mt-quotes has no quadgrams.txt
+	cf, err = CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil || len(cf.Quadgrams) != 0 {
+		t.Errorf("CharFreqFromFolder() = %d quadgrams, %v", len(cf.Quadgrams), err)
+	}
+}
+
+func TestThumbKeys(t *testing.T) {
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithThumbs(string(Blank) + " ")
+
+	if len(kb.Groups) != 10 || kb.Groups[LeftThumb] != "" || kb.Groups[RightThumb] != " " {
+		t.Errorf("Groups = %q, want an empty left thumb and space on the right thumb", kb.Groups)
+	}
+	if !kb.OnRight(' ') || kb.OnLeft(' ') {
+		t.Error("OnRight(' ') = false but want true")
+	}
+	if kb.WithLayout(kb.Layout).Thumbs != kb.Thumbs {
+		t.Error("WithLayout() lost the thumb keys")
+	}
+
+	moved := false
+	for i := 0; i < 200; i++ {
+		kb = MutateKeyboard(kb, 3, []int{})
+
+		thumbs := []rune(kb.Thumbs)
+		if !slices.Contains(thumbs, ' ') {
+			t.Fatalf("MutateKeyboard() moved space off the thumbs: %q", kb.Thumbs)
+		}
+		if unicode.IsLetter(thumbs[0]) || unicode.IsLetter(thumbs[1]) {
+			moved = true
+			if strings.Count(kb.Layout, string(Blank)) != 1 {
+				t.Fatalf("MutateKeyboard() = %s, want an empty key on the base layout", kb.Layout)
+			}
+		}
+		for _, r := range kb.Thumbs + kb.Layout {
+			if r != ' ' && r != Blank && !unicode.IsLetter(r) && !strings.ContainsRune(",./;'", r) {
+				t.Fatalf("MutateKeyboard() added character %q", r)
+			}
+		}
+	}
+
+	if !moved {
+		t.Error("MutateKeyboard() never put a letter on a thumb key")
+	}
+}
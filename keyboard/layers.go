@@ -0,0 +1,232 @@
+package keyboard
+
+import (
+	"slices"
+	"sync"
+	"unicode"
+)
+
+// Marks a key on a layer that has no character
+const Blank = '·'
+
+// Virtual characters for the shift keys, typed by the pinkies
+const (
+	LeftShift  = '⇧'
+	RightShift = '⇑'
+)
+
+// Short names of the fingers, in the same order as kb.Groups. The thumbs are only part of
+// keyboards that have thumb keys.
+var FingerNames = []string{"LP", "LR", "LM", "LI", "RI", "RM", "RR", "RP", "LT", "RT"}
+
+// Returns the index of a finger from its short name, ex. "LP" for the left pinky
+func FingerIndex(name string) int {
+	return slices.Index(FingerNames, name)
+}
+
+// A key held to activate a layer. Key is a virtual character standing for the key in n-grams,
+// and Finger is the index of the group that presses it.
+type Activator struct {
+	Key    rune
+	Finger int
+}
+
+// A layer of characters typed by holding an activation key and pressing the base key at the same position.
+type Layer struct {
+	Name string
+	// 31 characters in row form like a layout, Blank for keys without a character
+	Keys string
+	// Shifted layers hold the shifted version of the base layout, so their keys follow the base layout
+	Shifted bool
+	// The activator on the opposite hand of the pressed key is used if there is one, otherwise the first
+	Activators []Activator
+}
+
+// The shift layer of the US layout, activated by either shift key
+func ShiftLayer() Layer {
+	return Layer{
+		Name:    "shift",
+		Shifted: true,
+		Activators: []Activator{
+			{LeftShift, 0},
+			{RightShift, 7},
+		},
+	}
+}
+
+// A layer of symbols in row form, activated by a key pressed by finger
+func SymbolLayer(name string, keys string, key rune, finger int) Layer {
+	return Layer{
+		Name:       name,
+		Keys:       keys,
+		Activators: []Activator{{key, finger}},
+	}
+}
+
+// Creates a keyboard with the same layout, thumb keys and magic rules and the given layers
+func (k *Keyboard) WithLayers(layers ...Layer) *Keyboard {
+	return newKeyboard(k.Layout, k.Thumbs, layers, k.Magic)
+}
+
+var usShifted = map[rune]rune{
+	'`': '~', '1': '!', '2': '@', '3': '#', '4': '$', '5': '%', '6': '^', '7': '&', '8': '*',
+	'9': '(', '0': ')', '-': '_', '=': '+', '[': '{', ']': '}', '\\': '|',
+	';': ':', '\'': '"', ',': '<', '.': '>', '/': '?',
+}
+
+// Returns the character typed by holding shift on a key of the US layout, or Blank if there is none
+func ShiftRune(r rune) rune {
+	if shifted, ok := usShifted[r]; ok {
+		return shifted
+	}
+	if upper := unicode.ToUpper(r); upper != r {
+		return upper
+	}
+	return Blank
+}
+
+// Letters and Blank keys can't be moved between the base layout and layers
+func movableSymbol(r rune) bool {
+	return r != Blank && !unicode.IsLetter(r)
+}
+
+// Fills the keys of shifted layers from the base layout
+func buildLayers(layout string, layers []Layer) []Layer {
+	if len(layers) == 0 {
+		return nil
+	}
+
+	built := slices.Clone(layers)
+	for i, layer := range built {
+		if !layer.Shifted {
+			continue
+		}
+
+		keys := []rune(layout)
+		for j, r := range keys {
+			keys[j] = ShiftRune(r)
+		}
+		built[i].Keys = string(keys)
+	}
+	return built
+}
+
+func activators(layers []Layer) []Activator {
+	acts := []Activator{}
+	for _, layer := range layers {
+		for _, act := range layer.Activators {
+			if !slices.Contains(acts, act) {
+				acts = append(acts, act)
+			}
+		}
+	}
+	return acts
+}
+
+// Fingers [0, 4) and the left thumb belong to the left hand
+func FingerOnLeft(finger int) bool {
+	return finger < 4 || finger == 8
+}
+
+// Returns the keys pressed to type a character: the activator of its layer if it is on one,
+// followed by its key on the base layout. Characters that are not on the keyboard are returned as is.
+func (k *Keyboard) KeysFor(r rune) []rune {
+	if _, ok := k.GroupId[r]; ok || r == Blank {
+		return []rune{r}
+	}
+
+	base := []rune(k.Layout)
+	for _, layer := range k.Layers {
+		idx := slices.Index([]rune(layer.Keys), r)
+		if idx == -1 {
+			continue
+		}
+
+		act := layer.Activators[0]
+		onLeft := k.OnLeft(base[idx])
+		for _, a := range layer.Activators {
+			if FingerOnLeft(a.Finger) != onLeft {
+				act = a
+				break
+			}
+		}
+		return []rune{act.Key, base[idx]}
+	}
+
+	return []rune{r}
+}
+
+type strokeCache struct {
+	mu      sync.Mutex
+	strokes map[*CharFreq]*CharFreq
+}
+
+// Translates n-grams of characters into n-grams of the keys pressed to type them, so layer
+// activation keys and magic keys are part of the sequences. Keyboards without either return cf itself.
+// Results are cached per CharFreq, so cf should not be modified afterwards.
+func (k *Keyboard) Keystrokes(cf *CharFreq) *CharFreq {
+	if len(k.Layers) == 0 && !k.HasMagic() {
+		return cf
+	}
+
+	k.strokes.mu.Lock()
+	defer k.strokes.mu.Unlock()
+
+	if k.strokes.strokes == nil {
+		k.strokes.strokes = map[*CharFreq]*CharFreq{}
+	}
+	if out, ok := k.strokes.strokes[cf]; ok {
+		return out
+	}
+
+	out := &CharFreq{
+		Chars:     make(map[rune]int),
+		Bigrams:   make(map[string]int),
+		Trigrams:  make(map[string]int),
+		Quadgrams: make(map[string]int),
+	}
+
+	cf = k.applyMagic(cf)
+
+	memo := map[rune][]rune{}
+	keysFor := func(r rune) []rune {
+		keys, ok := memo[r]
+		if !ok {
+			keys = k.KeysFor(r)
+			memo[r] = keys
+		}
+		return keys
+	}
+
+	for c, val := range cf.Chars {
+		for _, key := range keysFor(c) {
+			out.Chars[key] += val
+		}
+	}
+	expandNgrams(cf.Bigrams, out.Bigrams, 2, keysFor)
+	expandNgrams(cf.Trigrams, out.Trigrams, 3, keysFor)
+	expandNgrams(cf.Quadgrams, out.Quadgrams, 4, keysFor)
+
+	k.strokes.strokes[cf] = out
+	return out
+}
+
+// Every n-gram of characters is expanded into keys, and the windows of n keys starting within
+// the keys of its first character are counted. Summed over a text, this counts every window of n
+// keys in the text typed out exactly once.
+func expandNgrams(src map[string]int, dst map[string]int, n int, keysFor func(rune) []rune) {
+	for ngram, val := range src {
+		keys := []rune{}
+		first := 0
+		for i, c := range ngram {
+			keys = append(keys, keysFor(c)...)
+			if i == 0 {
+				first = len(keys)
+			}
+		}
+
+		for i := 0; i < first && i+n <= len(keys); i++ {
+			dst[string(keys[i:i+n])] += val
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package keyboard
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestShiftLayer(t *testing.T) {
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithLayers(ShiftLayer())
+
+	if kb.Layers[0].Keys != "QWERTYUIOPASDFGHJKL:\"ZXCVBNM<>?" {
+		t.Errorf("ShiftLayer() keys = %s", kb.Layers[0].Keys)
+	}
+
+	// shift is pressed by the pinky of the opposite hand
+	expected := map[rune][]rune{
+		'T': {RightShift, 't'},
+		':': {LeftShift, ';'},
+		't': {'t'},
+		'!': {'!'},
+	}
+	for r, keys := range expected {
+		if !slices.Equal(kb.KeysFor(r), keys) {
+			t.Errorf("KeysFor(%q) = %q but want %q", r, kb.KeysFor(r), keys)
+		}
+	}
+
+	if !strings.ContainsRune(kb.Groups[0], LeftShift) || !strings.ContainsRune(kb.Groups[7], RightShift) {
+		t.Errorf("Groups = %v, shift keys are missing", kb.Groups)
+	}
+	if !kb.OnLeft(LeftShift) || !kb.OnRight(RightShift) {
+		t.Error("Shift keys are on the wrong hand")
+	}
+
+	cf := &CharFreq{
+		Chars:    map[rune]int{'T': 2, 'h': 3},
+		Bigrams:  map[string]int{"Th": 5, "hT": 1},
+		Trigrams: map[string]int{"The": 2},
+	}
+	strokes := kb.Keystrokes(cf)
+
+	expectedBigrams := map[string]int{"⇑t": 5, "th": 5, "h⇑": 1}
+	for key, val := range expectedBigrams {
+		if strokes.Bigrams[key] != val {
+			t.Errorf("Keystrokes() bigram %q = %d but want %d", key, strokes.Bigrams[key], val)
+		}
+	}
+
+	expectedTrigrams := map[string]int{"⇑th": 2, "the": 2}
+	for key, val := range expectedTrigrams {
+		if strokes.Trigrams[key] != val {
+			t.Errorf("Keystrokes() trigram %q = %d but want %d", key, strokes.Trigrams[key], val)
+		}
+	}
+
+	if strokes.Chars[RightShift] != 2 || strokes.Chars['t'] != 2 {
+		t.Errorf("Keystrokes() chars = %v", strokes.Chars)
+	}
+
+	if kb.Keystrokes(cf) != strokes {
+		t.Error("Keystrokes() is not cached")
+	}
+	if NewKeyboard(kb.Layout).Keystrokes(cf) != cf {
+		t.Error("Keystrokes() without layers should return cf")
+	}
+}
+
+func TestSymbolLayer(t *testing.T) {
+	symbols := "!@#$%^&*()" + strings.Repeat(string(Blank), 11) + "-=[]{}<>?:"
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithLayers(SymbolLayer("sym", symbols, '◆', FingerIndex("LI")))
+
+	if !slices.Equal(kb.KeysFor('('), []rune{'◆', 'o'}) {
+		t.Errorf("KeysFor('(') = %q", kb.KeysFor('('))
+	}
+
+	for i := 0; i < 50; i++ {
+		kb = MutateKeyboard(kb, 5, []int{})
+	}
+
+	all := []rune(kb.Layout + kb.Layers[0].Keys)
+	slices.Sort(all)
+	expected := []rune("qwertyuiopasdfghjkl;'zxcvbnm,./" + symbols)
+	slices.Sort(expected)
+	if !slices.Equal(all, expected) {
+		t.Errorf("MutateKeyboard() changed the characters: %s", string(all))
+	}
+
+	for _, r := range kb.Layers[0].Keys {
+		if unicode.IsLetter(r) {
+			t.Errorf("MutateKeyboard() moved letter %q onto a layer", r)
+		}
+	}
+	if strings.ContainsRune(kb.Layout, Blank) {
+		t.Errorf("MutateKeyboard() moved a blank key onto the base layout: %s", kb.Layout)
+	}
+}
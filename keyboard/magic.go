@@ -0,0 +1,201 @@
+package keyboard
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Virtual characters of the adaptive keys, placed on the layout or a thumb key like any other character.
+// The repeat key types the previous character again, the magic key types the character its rule
+// gives for the previous character.
+const (
+	MagicKey  = '★'
+	RepeatKey = '↻'
+)
+
+// Characters typed by the magic key, by the previous character
+type MagicRules map[rune]rune
+
+// Parses comma separated pairs of the previous character and the character the magic key types,
+// ex. "ea,ou" types "a" after "e" and "u" after "o"
+func ParseMagicRules(s string) (MagicRules, error) {
+	rules := MagicRules{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		runes := []rune(pair)
+		if len(runes) != 2 {
+			return nil, fmt.Errorf("invalid magic rule %q, want the previous character and the typed character", pair)
+		}
+		rules[runes[0]] = runes[1]
+	}
+	return rules, nil
+}
+
+// Rules as comma separated pairs, sorted by the previous character
+func (r MagicRules) String() string {
+	prevs := make([]rune, 0, len(r))
+	for prev := range r {
+		prevs = append(prevs, prev)
+	}
+	slices.Sort(prevs)
+
+	pairs := make([]string, 0, len(prevs))
+	for _, prev := range prevs {
+		pairs = append(pairs, string(prev)+string(r[prev]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Creates a keyboard with the same layout, layers and thumb keys and the given magic rules
+func (k *Keyboard) WithMagic(rules MagicRules) *Keyboard {
+	return newKeyboard(k.Layout, k.Thumbs, k.Layers, rules)
+}
+
+// Whether the keyboard has a repeat or magic key
+func (k *Keyboard) HasMagic() bool {
+	_, repeat := k.GroupId[RepeatKey]
+	_, magic := k.GroupId[MagicKey]
+	return repeat || magic
+}
+
+// Returns the key pressed to type c after prev: the repeat or magic key if either types it, otherwise c
+func (k *Keyboard) AdaptiveKey(prev rune, c rune) rune {
+	if _, ok := k.GroupId[RepeatKey]; ok && prev == c {
+		return RepeatKey
+	}
+	if _, ok := k.GroupId[MagicKey]; ok && k.Magic[prev] == c && prev != c {
+		return MagicKey
+	}
+	return c
+}
+
+// Rewrites n-grams so characters typed with the repeat or magic key are replaced by that key.
+// Every character but the first of an n-gram has its previous character in the n-gram. The character
+// before the first is unknown, so the count is split by how often the first character is typed with
+// each key overall, which is known from the bigrams.
+func (k *Keyboard) applyMagic(cf *CharFreq) *CharFreq {
+	if !k.HasMagic() {
+		return cf
+	}
+
+	// how often each character is typed by each key
+	typedBy := map[rune]map[rune]int{}
+	for bigram, val := range cf.Bigrams {
+		runes := []rune(bigram)
+		key := k.AdaptiveKey(runes[0], runes[1])
+		if key == runes[1] {
+			continue
+		}
+		if typedBy[runes[1]] == nil {
+			typedBy[runes[1]] = map[rune]int{}
+		}
+		typedBy[runes[1]][key] += val
+	}
+
+	// shares of a character's count typed with each key, the character's own key last
+	shares := func(c rune) ([]rune, []float64) {
+		keys := []rune{}
+		fracs := []float64{}
+		rest := 1.0
+		total := cf.Chars[c]
+
+		for _, key := range []rune{RepeatKey, MagicKey} {
+			val := typedBy[c][key]
+			if val == 0 || total == 0 {
+				continue
+			}
+			frac := min(float64(val)/float64(total), rest)
+			keys = append(keys, key)
+			fracs = append(fracs, frac)
+			rest -= frac
+		}
+		return append(keys, c), append(fracs, rest)
+	}
+
+	out := &CharFreq{
+		Chars:     make(map[rune]int),
+		Bigrams:   make(map[string]int),
+		Trigrams:  make(map[string]int),
+		Quadgrams: make(map[string]int),
+	}
+
+	for c, val := range cf.Chars {
+		keys, fracs := shares(c)
+		for i, key := range keys {
+			out.Chars[key] += int(math.Round(float64(val) * fracs[i]))
+		}
+	}
+
+	rewrite := func(src map[string]int, dst map[string]int) {
+		for ngram, val := range src {
+			runes := []rune(ngram)
+			rest := slices.Clone(runes)
+			for i := 1; i < len(runes); i++ {
+				rest[i] = k.AdaptiveKey(runes[i-1], runes[i])
+			}
+
+			keys, fracs := shares(runes[0])
+			for i, key := range keys {
+				rest[0] = key
+				if count := int(math.Round(float64(val) * fracs[i])); count > 0 {
+					dst[string(rest)] += count
+				}
+			}
+		}
+	}
+	rewrite(cf.Bigrams, out.Bigrams)
+	rewrite(cf.Trigrams, out.Trigrams)
+	rewrite(cf.Quadgrams, out.Quadgrams)
+
+	return out
+}
+
+// Creates a derivative keyboard by changing random magic rules. A rule is set for a random letter
+// of the layout to type another random letter, or removed when both letters are the same.
+func MutateMagic(k *Keyboard, changes int) *Keyboard {
+	letters := []rune{}
+	for _, r := range k.Layout + k.Thumbs {
+		if unicode.IsLetter(r) {
+			letters = append(letters, r)
+		}
+	}
+	if len(letters) == 0 {
+		return k
+	}
+
+	rules := maps.Clone(k.Magic)
+	if rules == nil {
+		rules = MagicRules{}
+	}
+
+	for i := 0; i < changes; i++ {
+		prev := letters[rand.Intn(len(letters))]
+		next := letters[rand.Intn(len(letters))]
+		if prev == next {
+			delete(rules, prev)
+		} else {
+			rules[prev] = next
+		}
+	}
+
+	return k.WithMagic(rules)
+}
+
+// Replaces a character of a row layout with key, ex. PlaceKey(qwerty, ';', MagicKey).
+// Layouts that have the key already, like annealed layouts, are left as they are.
+func PlaceKey(layout string, old rune, key rune) (string, error) {
+	if strings.ContainsRune(layout, key) {
+		return layout, nil
+	}
+	if !strings.ContainsRune(layout, old) {
+		return "", fmt.Errorf("layout %s has no %q to replace", layout, old)
+	}
+	return strings.Replace(layout, string(old), string(key), 1), nil
+}
@@ -0,0 +1,107 @@
+package keyboard
+
+import (
+	"testing"
+)
+
+func TestMagicKeystrokes(t *testing.T) {
+	layout, err := PlaceKey("qwertyuiopasdfghjkl;'zxcvbnm,./", ';', MagicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb := NewKeyboard(layout).WithMagic(MagicRules{'e': 'a'})
+
+	cf := &CharFreq{
+		Chars:    map[rune]int{'e': 10, 'a': 10, 't': 5},
+		Bigrams:  map[string]int{"ea": 4, "at": 5},
+		Trigrams: map[string]int{"eat": 4},
+	}
+	strokes := kb.Keystrokes(cf)
+
+	expectedChars := map[rune]int{'e': 10, 'a': 6, MagicKey: 4, 't': 5}
+	for c, val := range expectedChars {
+		if strokes.Chars[c] != val {
+			t.Errorf("Keystrokes() chars %q = %d but want %d", c, strokes.Chars[c], val)
+		}
+	}
+
+	// "a" is typed with the magic key 40% of the time, which is split off "at"
+	expectedBigrams := map[string]int{"e★": 4, "ea": 0, "at": 3, "★t": 2}
+	for key, val := range expectedBigrams {
+		if strokes.Bigrams[key] != val {
+			t.Errorf("Keystrokes() bigram %q = %d but want %d", key, strokes.Bigrams[key], val)
+		}
+	}
+
+	if strokes.Trigrams["e★t"] != 4 || strokes.Trigrams["eat"] != 0 {
+		t.Errorf("Keystrokes() trigrams = %v", strokes.Trigrams)
+	}
+
+	// rules have no effect without a magic key on the keyboard
+	if NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithMagic(MagicRules{'e': 'a'}).Keystrokes(cf) != cf {
+		t.Error("Keystrokes() without a magic key should return cf")
+	}
+}
+
+func TestRepeatKeystrokes(t *testing.T) {
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithThumbs(string(RepeatKey) + " ")
+
+	cf := &CharFreq{
+		Chars:    map[rune]int{'l': 4, 'o': 4},
+		Bigrams:  map[string]int{"ll": 4, "ol": 4},
+		Trigrams: map[string]int{"oll": 4},
+	}
+	strokes := kb.Keystrokes(cf)
+
+	if strokes.Trigrams["ol↻"] != 4 || strokes.Bigrams["o"+"l"] != 4 || strokes.Chars[RepeatKey] != 4 {
+		t.Errorf("Keystrokes() = %v %v %v", strokes.Chars, strokes.Bigrams, strokes.Trigrams)
+	}
+	if kb.GroupId[RepeatKey] != LeftThumb {
+		t.Errorf("GroupId[RepeatKey] = %d but want %d", kb.GroupId[RepeatKey], LeftThumb)
+	}
+}
+
+func TestMagicRules(t *testing.T) {
+	rules, err := ParseMagicRules("ou,ea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules['o'] != 'u' || rules['e'] != 'a' || rules.String() != "ea,ou" {
+		t.Errorf("ParseMagicRules() = %v", rules)
+	}
+
+	if _, err := ParseMagicRules("eat"); err == nil {
+		t.Error("ParseMagicRules(\"eat\") should fail")
+	}
+
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithMagic(rules)
+	mutated := MutateMagic(kb, 20)
+	if len(kb.Magic) != 2 || kb.Magic['o'] != 'u' {
+		t.Error("MutateMagic() modified the original rules")
+	}
+	if mutated.Layout != kb.Layout || mutated.Magic.String() == rules.String() {
+		t.Errorf("MutateMagic() = %s, %s", mutated.Layout, mutated.Magic)
+	}
+}
+
+func TestPlaceKey(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{"qwertyuiopasdfghjkl;'zxcvbnm,./", "qwertyuiopasdfghjkl★'zxcvbnm,./"},
+		// the magic key was moved by annealing, so ; is elsewhere
+		{"qwertyuiop★sdfghjkl;'zxcvbnm,./", "qwertyuiop★sdfghjkl;'zxcvbnm,./"},
+		{"qwertyuiop★sdfghjkla'zxcvbnm,./", "qwertyuiop★sdfghjkla'zxcvbnm,./"},
+	}
+	for _, test := range tests {
+		got, err := PlaceKey(test.layout, ';', MagicKey)
+		if err != nil || got != test.want {
+			t.Errorf("PlaceKey(%s) = %s, %v but want %s", test.layout, got, err, test.want)
+		}
+	}
+
+	if _, err := PlaceKey("qwertyuiopasdfghjkla'zxcvbnm,./", ';', MagicKey); err == nil {
+		t.Error("PlaceKey() of a missing character should fail")
+	}
+}
@@ -0,0 +1,43 @@
+package keyboard
+
+import "strings"
+
+// Indexes of the thumb groups in kb.Groups
+const (
+	LeftThumb  = 8
+	RightThumb = 9
+)
+
+// Creates a keyboard with the same layout, layers and magic rules and the given thumb keys. thumbs holds the left thumb key
+// followed by the right thumb key, Blank for a thumb key without a character.
+// ex. "· " puts space on the right thumb, and "e " the letter e on the left thumb as well.
+func (k *Keyboard) WithThumbs(thumbs string) *Keyboard {
+	return newKeyboard(k.Layout, thumbs, k.Layers, k.Magic)
+}
+
+// The keys of the left and right thumb groups. Blank keys are not typed, so they are left out.
+func thumbGroups(thumbs string) []string {
+	groups := []string{"", ""}
+	for i, r := range []rune(thumbs) {
+		if r != Blank && i < 2 {
+			groups[i] += string(r)
+		}
+	}
+	return groups
+}
+
+func thumbLabel(r rune) string {
+	if r == ' ' {
+		return "␣"
+	}
+	return string(r)
+}
+
+// Thumb keys centered below the layout
+func (k *Keyboard) thumbString() string {
+	labels := []string{}
+	for _, r := range k.Thumbs {
+		labels = append(labels, thumbLabel(r))
+	}
+	return strings.Repeat(" ", 9) + strings.Join(labels, "  ")
+}
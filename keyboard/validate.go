@@ -0,0 +1,247 @@
+package keyboard
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Letters every layout is checked for by default
+const DefaultAlphabet = "abcdefghijklmnopqrstuvwxyz"
+
+// Geometries and finger maps layouts can be made for, the first of each is the default
+var (
+	Geometries = []string{"rowstag"}
+	FingerMaps = []string{"standard"}
+)
+
+// A layout that does not have 31 characters
+type LengthError struct {
+	Length int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("layout has %d characters, want 31", e.Length)
+}
+
+// A character on more than one key, with the indexes of the keys in the row layout
+type DuplicateKeyError struct {
+	Key     rune
+	Indexes []int
+}
+
+func (e *DuplicateKeyError) Error() string {
+	keys := make([]string, len(e.Indexes))
+	for i, index := range e.Indexes {
+		row, col := RowCol(index)
+		keys[i] = fmt.Sprintf("row %d column %d", row+1, col+1)
+	}
+	return fmt.Sprintf("%q is on %s", e.Key, strings.Join(keys, " and "))
+}
+
+// A character on a thumb key or a layer that is on another key of the keyboard as well, with the
+// keys it is on
+type RepeatedKeyError struct {
+	Key  rune
+	Keys []string
+}
+
+func (e *RepeatedKeyError) Error() string {
+	return fmt.Sprintf("%q is on %s", e.Key, strings.Join(e.Keys, " and "))
+}
+
+// Letters of the alphabet that no key types
+type MissingLettersError struct {
+	Letters string
+}
+
+func (e *MissingLettersError) Error() string {
+	return fmt.Sprintf("letters %s are missing", e.Letters)
+}
+
+// Characters on keys that never occur in the frequency data, so the keys are wasted
+type AbsentKeysError struct {
+	Keys string
+}
+
+func (e *AbsentKeysError) Error() string {
+	return fmt.Sprintf("keys %s are never typed in the frequency data", e.Keys)
+}
+
+// A geometry or finger map that keyboards can't be built for
+type UnknownGeometryError struct {
+	Kind string
+	Name string
+}
+
+func (e *UnknownGeometryError) Error() string {
+	known := Geometries
+	if e.Kind == "finger map" {
+		known = FingerMaps
+	}
+	return fmt.Sprintf("unknown %s %s, use one of %s", e.Kind, e.Name, strings.Join(known, ", "))
+}
+
+// Every problem of a layout, by its name
+type LayoutError struct {
+	Name     string
+	Problems []error
+}
+
+func (e *LayoutError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.Error()
+	}
+	if e.Name == "" {
+		return "invalid layout: " + strings.Join(problems, "; ")
+	}
+	return fmt.Sprintf("invalid layout %s: %s", e.Name, strings.Join(problems, "; "))
+}
+
+func (e *LayoutError) Unwrap() []error {
+	return e.Problems
+}
+
+// Creates a keyboard like NewKeyboard, returning a *LayoutError if the layout does not have
+// 31 characters or has a character other than Blank on more than one key
+func ParseKeyboard(layout string) (*Keyboard, error) {
+	if problems := layoutProblems(layout); len(problems) > 0 {
+		return nil, &LayoutError{Problems: problems}
+	}
+	return newKeyboard(layout, "", nil, nil), nil
+}
+
+func layoutProblems(layout string) []error {
+	if n := utf8.RuneCountInString(layout); n != 31 {
+		return []error{&LengthError{n}}
+	}
+
+	problems := []error{}
+	indexes := map[rune][]int{}
+	keys := []rune(layout)
+	for i, r := range keys {
+		indexes[r] = append(indexes[r], i)
+	}
+	for i, r := range keys {
+		if r != Blank && len(indexes[r]) > 1 && indexes[r][0] == i {
+			problems = append(problems, &DuplicateKeyError{r, indexes[r]})
+		}
+	}
+	return problems
+}
+
+// Finds the characters other than Blank on the thumb keys and layers that are on more than one key
+// of the keyboard. Characters only repeated within the layout are left to layoutProblems.
+func repeatedKeyProblems(layout string, thumbs string, layers map[string]string) []error {
+	type key struct {
+		char rune
+		name string
+	}
+	keys := []key{}
+	for i, r := range []rune(layout) {
+		row, col := RowCol(i)
+		keys = append(keys, key{r, fmt.Sprintf("row %d column %d", row+1, col+1)})
+	}
+	for i, r := range []rune(thumbs) {
+		keys = append(keys, key{r, fmt.Sprintf("thumb key %d", i+1)})
+	}
+	names := make([]string, 0, len(layers))
+	for name := range layers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for i, r := range []rune(layers[name]) {
+			row, col := RowCol(i)
+			keys = append(keys, key{r, fmt.Sprintf("row %d column %d of layer %s", row+1, col+1, name)})
+		}
+	}
+
+	layoutLength := utf8.RuneCountInString(layout)
+	byChar := map[rune][]int{}
+	for i, k := range keys {
+		byChar[k.char] = append(byChar[k.char], i)
+	}
+	problems := []error{}
+	for i, k := range keys {
+		indexes := byChar[k.char]
+		if k.char == Blank || len(indexes) < 2 || indexes[0] != i || indexes[len(indexes)-1] < layoutLength {
+			continue
+		}
+		places := make([]string, len(indexes))
+		for j, index := range indexes {
+			places[j] = keys[index].name
+		}
+		problems = append(problems, &RepeatedKeyError{k.char, places})
+	}
+	return problems
+}
+
+// Returns a *MissingLettersError if a letter of alphabet is not typed by any key of the
+// keyboard, including its layers
+func (k *Keyboard) CheckAlphabet(alphabet string) error {
+	missing := []rune{}
+	for _, r := range alphabet {
+		if _, ok := k.GroupId[r]; ok || slices.ContainsFunc(k.Layers, func(l Layer) bool {
+			return strings.ContainsRune(l.Keys, r)
+		}) {
+			continue
+		}
+		missing = append(missing, r)
+	}
+	if len(missing) > 0 {
+		return &MissingLettersError{string(missing)}
+	}
+	return nil
+}
+
+// Returns an *AbsentKeysError if characters of the base layout never occur in the frequency data
+func (k *Keyboard) CheckCorpus(cf *CharFreq) error {
+	absent := []rune{}
+	for _, r := range k.Layout {
+		if r != Blank && r != RepeatKey && r != MagicKey && cf.Chars[r] == 0 {
+			absent = append(absent, r)
+		}
+	}
+	if len(absent) > 0 {
+		return &AbsentKeysError{string(absent)}
+	}
+	return nil
+}
+
+// Returns an *UnknownGeometryError for a geometry or finger map other than the known ones,
+// empty names are the defaults
+func CheckGeometry(geometry string, fingerMap string) error {
+	if geometry != "" && !slices.Contains(Geometries, geometry) {
+		return &UnknownGeometryError{"geometry", geometry}
+	}
+	if fingerMap != "" && !slices.Contains(FingerMaps, fingerMap) {
+		return &UnknownGeometryError{"finger map", fingerMap}
+	}
+	return nil
+}
+
+// Checks the layout of a keyboard, its thumb keys, the keys of its layers by name and its geometry,
+// returning a *LayoutError with every problem found, or nil. Letters of alphabet are checked as well
+// if it is not empty.
+func Validate(name string, layout string, thumbs string, layers map[string]string, geometry string, fingerMap string, alphabet string) error {
+	problems := layoutProblems(layout)
+	if utf8.RuneCountInString(layout) == 31 {
+		problems = append(problems, repeatedKeyProblems(layout, thumbs, layers)...)
+	}
+	if err := CheckGeometry(geometry, fingerMap); err != nil {
+		problems = append(problems, err)
+	}
+	if alphabet != "" && utf8.RuneCountInString(layout) == 31 {
+		if err := newKeyboard(layout, "", nil, nil).CheckAlphabet(alphabet); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &LayoutError{name, problems}
+	}
+	return nil
+}
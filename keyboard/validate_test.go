@@ -0,0 +1,120 @@
+package keyboard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyboard(t *testing.T) {
+	if _, err := ParseKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./"); err != nil {
+		t.Errorf("ParseKeyboard(qwerty) = %s", err)
+	}
+	// empty keys may repeat
+	if _, err := ParseKeyboard("qwertyuiopasdfghjkl··zxcvbnm···"); err != nil {
+		t.Errorf("ParseKeyboard() with Blank keys = %s", err)
+	}
+
+	_, err := ParseKeyboard("qwerty")
+	var lengthErr *LengthError
+	if !errors.As(err, &lengthErr) || lengthErr.Length != 6 {
+		t.Errorf("ParseKeyboard(qwerty) = %v but want a LengthError of 6", err)
+	}
+
+	_, err = ParseKeyboard("qwertyuiopasdfghjkl;qzxcvbnm,.a")
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) || len(layoutErr.Problems) != 2 {
+		t.Fatalf("ParseKeyboard() with 2 duplicates = %v but want 2 problems", err)
+	}
+	var dupErr *DuplicateKeyError
+	if !errors.As(layoutErr.Problems[0], &dupErr) || dupErr.Key != 'q' || len(dupErr.Indexes) != 2 || dupErr.Indexes[1] != 20 {
+		t.Errorf("ParseKeyboard() first problem = %v but want q on 0 and 20", layoutErr.Problems[0])
+	}
+}
+
+func TestNewKeyboardPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewKeyboard() with a duplicate key should panic")
+		}
+	}()
+	NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,.q")
+}
+
+func TestValidate(t *testing.T) {
+	err := Validate("dup", "qwertyuiopasdfghjkl;'qxcvbnm,./", "", nil, "ortho", "", DefaultAlphabet)
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) || layoutErr.Name != "dup" {
+		t.Fatalf("Validate() = %v but want a LayoutError of dup", err)
+	}
+
+	var dupErr *DuplicateKeyError
+	var geometryErr *UnknownGeometryError
+	var missingErr *MissingLettersError
+	if !errors.As(err, &dupErr) || !errors.As(err, &geometryErr) || !errors.As(err, &missingErr) {
+		t.Errorf("Validate() = %v but want a duplicate key, an unknown geometry and missing letters", err)
+	}
+	if missingErr != nil && missingErr.Letters != "z" {
+		t.Errorf("Validate() missing letters = %s but want z", missingErr.Letters)
+	}
+
+	if err := Validate("qwerty", "qwertyuiopasdfghjkl;'zxcvbnm,./", "", nil, "rowstag", "standard", DefaultAlphabet); err != nil {
+		t.Errorf("Validate(qwerty) = %s", err)
+	}
+	if err := Validate("short", "qwerty", "", nil, "", "", DefaultAlphabet); !errors.As(err, new(*LengthError)) {
+		t.Errorf("Validate(short) = %v but want a LengthError", err)
+	}
+}
+
+func TestValidateThumbsAndLayers(t *testing.T) {
+	// e is on the base layout and a thumb key, ! on two layers
+	layers := map[string]string{"numbers": "1234567890!····················", "symbols": "!@#····························"}
+	err := Validate("thumbs", "qwertyuiopasdfghjkl;'zxcvbnm,./", "e ", layers, "", "", DefaultAlphabet)
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) || len(layoutErr.Problems) != 2 {
+		t.Fatalf("Validate() = %v but want 2 problems", err)
+	}
+	var repeatedErr *RepeatedKeyError
+	if !errors.As(layoutErr.Problems[0], &repeatedErr) || repeatedErr.Key != 'e' ||
+		!reflect.DeepEqual(repeatedErr.Keys, []string{"row 1 column 3", "thumb key 1"}) {
+		t.Errorf("Validate() first problem = %v but want e on row 1 column 3 and thumb key 1", layoutErr.Problems[0])
+	}
+	if !errors.As(layoutErr.Problems[1], &repeatedErr) || repeatedErr.Key != '!' {
+		t.Errorf("Validate() second problem = %v but want ! on both layers", layoutErr.Problems[1])
+	}
+
+	// keys moved off the base layout are fine
+	err = Validate("thumbs", "qw·rtyuiopasdfghjkl;'zxcvbnm,./", "e ", map[string]string{"symbols": "!@#····························"}, "", "", "")
+	if err != nil {
+		t.Errorf("Validate() with e on a thumb key = %s", err)
+	}
+}
+
+func TestCheckAlphabet(t *testing.T) {
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	err := kb.CheckAlphabet("abcäöü")
+	var missingErr *MissingLettersError
+	if !errors.As(err, &missingErr) || missingErr.Letters != "äöü" {
+		t.Errorf("CheckAlphabet() = %v but want äöü missing", err)
+	}
+
+	// letters on layers count
+	layered := kb.WithLayers(SymbolLayer("umlauts", "äöü····························", '◆', 0))
+	if err := layered.CheckAlphabet("abcäöü"); err != nil {
+		t.Errorf("CheckAlphabet() with a layer = %s", err)
+	}
+}
+
+func TestCheckCorpus(t *testing.T) {
+	kb := NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	cf := &CharFreq{Chars: map[rune]int{}}
+	for _, r := range "qwertyuiopasdfghjklzxcvbnm,." {
+		cf.Chars[r] = 1
+	}
+
+	err := kb.CheckCorpus(cf)
+	var absentErr *AbsentKeysError
+	if !errors.As(err, &absentErr) || absentErr.Keys != ";'/" {
+		t.Errorf("CheckCorpus() = %v but want ;'/ absent", err)
+	}
+}
@@ -2,12 +2,13 @@ package main
 
 import (
 	"cmp"
-	"flag"
 	"fmt"
 	kbd "kbannealing/keyboard"
 	m "kbannealing/metrics"
+	"os"
 	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 func SortedKeys[K cmp.Ordered, T any](dict map[K]T) []K {
@@ -21,141 +22,226 @@ func SortedKeys[K cmp.Ordered, T any](dict map[K]T) []K {
 	return keys
 }
 
-func ProcessStats(keyboards map[string]*kbd.Keyboard, cf *kbd.CharFreq, order []string) {
-	bigramSum := 0
-	trigramSum := 0
-
-	for _, val := range cf.Bigrams {
-		bigramSum += val
-	}
-
-	for _, val := range cf.Trigrams {
-		trigramSum += val
+// Stats of the registry available for the frequency data, the columns of the stats table
+func statDescriptors(cf *kbd.CharFreq) []m.Descriptor {
+	stats := []m.Descriptor{}
+	for _, d := range m.Registry() {
+		if d.Stat && d.Available(cf) {
+			stats = append(stats, d)
+		}
 	}
+	return stats
+}
 
-	// header
-	fmt.Printf("%-23s %-10s %-10s %-10s %-10s %-10s\n", "Keyboard", "Alternate", "Roll", "SFB", "3Roll", "A+R+-S")
-	fmt.Println(strings.Repeat("-", 65))
-
+func ProcessStats(keyboards map[string]*kbd.Keyboard, cf *kbd.CharFreq, order []string) StatMap {
+	stats := statDescriptors(cf)
 	statMap := StatMap{}
 
 	for _, name := range order {
-		kb := keyboards[name]
-		stats := m.AllMetrics(kb, cf)
-		statMap[name] = make(map[string]float64)
-
-		for key, val := range stats {
-			if key == "sfb" {
-				statMap[name][key] = float64(val) / float64(bigramSum) * 100
-			} else {
-				statMap[name][key] = float64(val) / float64(trigramSum) * 100
-			}
-		}
+		statMap[name] = m.Stats(keyboards[name], cf)
 	}
 
-	saveStatsToJSON("stats.json", statMap)
+	// header
+	fmt.Printf("%-23s ", "Keyboard")
+	for _, d := range stats {
+		fmt.Printf("%-11s ", d.Name)
+	}
+	fmt.Printf("%-11s\n", "A+R+-S")
+	fmt.Println(strings.Repeat("-", 24+12*(len(stats)+1)))
 
 	for _, name := range order {
 		metrics := statMap[name]
 
 		fmt.Printf("%-23s ", name)
-
-		alternate := metrics["alternate"]
-		roll := metrics["roll"]
-		sfb := metrics["sfb"]
-		threeRoll := metrics["3roll"]
-
-		fmt.Printf("%-10.2f %-10.2f %-10.2f %-10.2f %-10.2f\n", alternate, roll, sfb, threeRoll, alternate+roll-sfb)
+		for _, d := range stats {
+			fmt.Printf("%-11.2f ", metrics[d.Name])
+		}
+		fmt.Printf("%-11.2f\n", metrics["alternate"]+metrics["roll"]-metrics["sfb"])
 	}
+
+	return statMap
 }
 
-func main() {
-	annealFlag := flag.Bool("anneal", false, "Use simulated annealing. This will overwrite 000 optimized layouts.")
-	textFlag := flag.String("text", "", "Use a wordlist txt file for data")
-	folderFlag := flag.String("folder", "CharFreqData/mt-quotes", "Use a folder for data, containing monograms, bigrams, and trigrams.txt")
-	lockSymbolsFlag := flag.Bool("symbollock", false, "Lock symbols in the keyboard to qwerty's layout")
+func ProcessLoad(keyboards map[string]*kbd.Keyboard, cf *kbd.CharFreq, order []string, target m.LoadTarget) {
+	// thumbs are only shown if a keyboard has them
+	fingers := 0
+	for _, kb := range keyboards {
+		fingers = max(fingers, len(kb.Groups))
+	}
 
-	flag.Parse()
+	// header
+	fmt.Printf("%-23s ", "Finger Usage")
+	for _, finger := range m.FingerNames[:fingers] {
+		fmt.Printf("%-6s ", finger)
+	}
+	fmt.Printf("%-6s %-6s %-6s\n", "Left", "Right", "Dev")
+	fmt.Println(strings.Repeat("-", 100))
 
-	if *textFlag != "" && *folderFlag != "CharFreqData/mt-quotes" {
-		fmt.Print("Cannot use both -text and -folder for frequency data.")
-		return
+	fmt.Printf("%-23s ", "target")
+	for _, val := range target {
+		fmt.Printf("%-6.1f ", val)
 	}
+	fmt.Println()
+
+	deviation := m.LoadDeviation(target)
+	for _, name := range order {
+		kb := keyboards[name]
+		left, right := m.HandUsage(kb, cf)
 
-	var cf *kbd.CharFreq
-	var err error
-	if *textFlag != "" {
-		cf, err = kbd.NewCharFreq(*textFlag)
-	} else {
-		cf, err = kbd.CharFreqFromFolder(*folderFlag)
+		usage := m.FingerUsage(kb, cf)
+		fmt.Printf("%-23s ", name)
+		for i := 0; i < fingers; i++ {
+			if i < len(usage) {
+				fmt.Printf("%-6.1f ", usage[i])
+			} else {
+				fmt.Printf("%-6s ", "-")
+			}
+		}
+		fmt.Printf("%-6.1f %-6.1f %-6.2f\n", left, right, float64(deviation(kb, cf))/100)
 	}
+}
 
-	if err != nil {
-		fmt.Printf("Could not load frequency data due to error: %s\n", err)
-		return
+func ProcessSimulation(keyboards map[string]*kbd.Keyboard, text *m.Text, order []string) {
+	fingers := 0
+	for _, kb := range keyboards {
+		fingers = max(fingers, len(kb.Groups))
 	}
 
-	combinedMetric := func(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
-		return -m.SfbScore(kb, cf) + m.AlternateScore(kb, cf) + m.RollScore(kb, cf)
+	// header
+	fmt.Printf("%-23s %-8s %-8s %-6s %-6s %-6s ", "Simulation", "Travel", "Per Key", "SF1", "SF2", "SF3")
+	for _, finger := range m.FingerNames[:fingers] {
+		fmt.Printf("%-6s ", finger)
 	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("-", 100))
 
-	layouts, err := loadLayoutFromJSON("layouts.json")
-	if err != nil {
-		fmt.Print("Could not load layout JSON")
-		return
+	for _, name := range order {
+		sim := text.Simulate(keyboards[name])
+
+		fmt.Printf("%-23s %-8.0f %-8.3f %-6.2f %-6.2f %-6.2f ", name, sim.Travel, sim.TravelPerKey(),
+			sim.SameFingerRate(1), sim.SameFingerRate(2), sim.SameFingerRate(3))
+		for i := 0; i < fingers; i++ {
+			if i < len(sim.FingerTravel) {
+				fmt.Printf("%-6.0f ", sim.FingerTravel[i])
+			} else {
+				fmt.Printf("%-6s ", "-")
+			}
+		}
+		fmt.Println()
 	}
+}
 
-	annealedKeyboards := map[string]*kbd.Keyboard{}
-	if *annealFlag {
-		fmt.Println("Finding optimal keyboards...")
-		startTemp := 1000000.0
+// Prints the stats with their confidence intervals from resampling the frequency data. Each
+// stat marks its best layout with ^, and with = the layouts that don't differ significantly from it.
+func ProcessBootstrap(keyboards map[string]*kbd.Keyboard, cf *kbd.CharFreq, order []string, opts m.BootstrapOptions) {
+	stats := statDescriptors(cf)
+	result := m.Bootstrap(keyboards, cf, stats, opts)
+
+	fmt.Printf("Bootstrap of %d resampled corpora, value ± half of the %.0f%% interval\n", opts.Samples, opts.Confidence*100)
+	fmt.Println("^ best layout of a stat, = not significantly different from the best")
+
+	best := map[string]string{}
+	for _, d := range stats {
+		for _, name := range order {
+			value, bestValue := result.Interval(name, d.Name).Value, result.Interval(best[d.Name], d.Name).Value
+			if best[d.Name] == "" || (d.LowerIsBetter && value < bestValue) || (!d.LowerIsBetter && value > bestValue) {
+				best[d.Name] = name
+			}
+		}
+	}
 
-		fmt.Println("Optimizing for minimum sfb...")
-		annealedKeyboards["000 optimized sfb"] = kbd.OptimizeHomerow(
-			SimulatedAnnealing(m.SfbScore, startTemp, cf, true, *lockSymbolsFlag), cf, *lockSymbolsFlag, false)
+	// header
+	fmt.Printf("%-23s ", "Keyboard")
+	for _, d := range stats {
+		fmt.Printf("%-14s ", d.Name)
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("-", 24+15*len(stats)))
 
-		fmt.Println("Optimizing for alternate hand use...")
-		annealedKeyboards["000 optimized alternate"] = kbd.OptimizeHomerow(
-			SimulatedAnnealing(m.AlternateScore, startTemp, cf, false, *lockSymbolsFlag), cf, *lockSymbolsFlag, false)
+	for _, name := range order {
+		fmt.Printf("%-23s ", name)
+		for _, d := range stats {
+			interval := result.Interval(name, d.Name)
+			mark := ""
+			if name == best[d.Name] {
+				mark = "^"
+			} else if !result.Significant(name, best[d.Name], d.Name) {
+				mark = "="
+			}
+			cell := fmt.Sprintf("%.2f±%.2f%s", interval.Value, (interval.High-interval.Low)/2, mark)
+			fmt.Printf("%s%s ", cell, strings.Repeat(" ", max(0, 14-utf8.RuneCountInString(cell))))
+		}
+		fmt.Println()
+	}
+}
 
-		fmt.Println("Optimizing for maximum roll...")
-		annealedKeyboards["000 optimized roll"] = kbd.OptimizeHomerow(
-			SimulatedAnnealing(m.RollScore, startTemp, cf, false, *lockSymbolsFlag), cf, *lockSymbolsFlag, false)
+// Prints a table of each stat by layout and corpus, and the robustness score of every layout
+func ProcessRobustness(matrix *m.CorpusMatrix) {
+	widths := make([]int, len(matrix.Corpora))
+	for i, name := range matrix.Corpora {
+		widths[i] = max(10, utf8.RuneCountInString(name))
+	}
 
-		fmt.Println("Optimizing for 3roll...")
-		annealedKeyboards["000 optimized 3roll"] = kbd.OptimizeHomerow(
-			SimulatedAnnealing(m.ThreeRollScore, startTemp, cf, false, *lockSymbolsFlag), cf, *lockSymbolsFlag, true)
+	for _, d := range matrix.Stats {
+		fmt.Printf("%-23s ", d.Name)
+		for i, name := range matrix.Corpora {
+			fmt.Printf("%-*s ", widths[i], name)
+		}
+		fmt.Println("Variation")
+		width := 24 + len("Variation")
+		for _, w := range widths {
+			width += w + 1
+		}
+		fmt.Println(strings.Repeat("-", width))
 
-		fmt.Println("Optimizing for combined metrics... (maximizing altrernate + roll - sfb)")
-		annealedKeyboards["000 optimized combined"] = kbd.OptimizeHomerow(
-			SimulatedAnnealing(combinedMetric, startTemp, cf, false, *lockSymbolsFlag), cf, *lockSymbolsFlag, false)
+		for _, layout := range matrix.Layouts {
+			fmt.Printf("%-23s ", layout)
+			for i, value := range matrix.Values[d.Name][layout] {
+				fmt.Printf("%-*.2f ", widths[i], value)
+			}
+			fmt.Printf("%.1f%%\n", matrix.Variation(d.Name, layout))
+		}
+		fmt.Println()
 	}
 
-	keyboards := map[string]*kbd.Keyboard{}
-	for name, layout := range layouts {
-		keyboards[name] = kbd.NewKeyboard(layout)
+	fmt.Println("Robustness: 100 minus the average variation of the stats across corpora, relative to the")
+	fmt.Println("average layout on each corpus. Layouts that overfit their corpus score lower.")
+	fmt.Printf("%-23s ", "Keyboard")
+	for _, d := range matrix.Stats {
+		fmt.Printf("%-11s ", d.Name)
 	}
-	for name, kb := range annealedKeyboards {
-		keyboards[name] = kb
+	fmt.Println("Robustness")
+	fmt.Println(strings.Repeat("-", 24+12*(len(matrix.Stats)+1)))
+	for _, layout := range matrix.Layouts {
+		fmt.Printf("%-23s ", layout)
+		for _, d := range matrix.Stats {
+			fmt.Printf("%-11s ", fmt.Sprintf("%.1f%%", matrix.Variation(d.Name, layout)))
+		}
+		fmt.Printf("%.1f\n", matrix.Robustness(layout))
 	}
+}
 
-	order := SortedKeys(keyboards)
-	ProcessStats(keyboards, cf, order)
+func main() {
+	args := os.Args[1:]
+	name := "compare"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	fmt.Println(strings.Repeat("-", 65))
+	if name == "help" {
+		usage()
+		return
+	}
 
-	for name, kb := range keyboards {
-		if len(name) >= 3 && name[:3] == "000" {
-			fmt.Println(name)
-			kb.PrintKeyboard()
-			fmt.Println(strings.Repeat("-", 65))
-		}
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Printf("Unknown command %s\n\n", name)
+		usage()
+		os.Exit(2)
 	}
 
-	if *annealFlag {
-		for name, kb := range annealedKeyboards {
-			layouts[name] = kb.Layout
-		}
-		saveLayoutToJSON("layouts.json", layouts)
+	if err := cmd.execute(args); err != nil {
+		fmt.Printf("%s: %s\n", name, err)
+		os.Exit(1)
 	}
 }
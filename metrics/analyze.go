@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	kbd "kbannealing/keyboard"
+	"slices"
+	"strings"
+)
+
+// An n-gram and its share of all n-grams of its length, in percent
+type NgramShare struct {
+	Ngram string  `json:"ngram"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// The n-grams matching a metric, with their total share and the most frequent ones
+type CategoryReport struct {
+	Name  string       `json:"name"`
+	Total float64      `json:"total"`
+	Top   []NgramShare `json:"top"`
+}
+
+// Usage of a finger and the same finger bigrams it types, in percent
+type FingerReport struct {
+	Finger string  `json:"finger"`
+	Keys   string  `json:"keys"`
+	Usage  float64 `json:"usage"`
+	Sfb    float64 `json:"sfb"`
+}
+
+// Detailed breakdown of the metrics of a layout
+type Report struct {
+	Layout     string           `json:"layout"`
+	Categories []CategoryReport `json:"categories"`
+	Fingers    []FingerReport   `json:"fingers"`
+}
+
+var categories = []struct {
+	name  string
+	n     int
+	match func(*kbd.Keyboard, string) bool
+}{
+	{"sfb", 2, isSfb},
+	{"scissor", 2, isScissor},
+	{"redirect", 3, isRedirect},
+	{"alternate", 3, isAlternate},
+}
+
+func sum(ngrams map[string]int) int {
+	total := 0
+	for _, val := range ngrams {
+		total += val
+	}
+	return total
+}
+
+func share(val int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(val) / float64(total) * 100
+}
+
+// Lists the top n-grams contributing to each metric of a layout and the load of each finger.
+// N-grams are of the keys pressed, so they include layer and adaptive keys. Shares are of all
+// n-grams of cf, like the percentages printed by the stats table.
+func Analyze(name string, kb *kbd.Keyboard, cf *kbd.CharFreq, top int) *Report {
+	strokes := kb.Keystrokes(cf)
+	totals := map[int]int{2: sum(cf.Bigrams), 3: sum(cf.Trigrams)}
+	ngrams := map[int]map[string]int{2: strokes.Bigrams, 3: strokes.Trigrams}
+
+	report := &Report{Layout: name}
+	for _, category := range categories {
+		matched := []NgramShare{}
+		count := 0
+		for seq, val := range ngrams[category.n] {
+			if category.match(kb, seq) {
+				matched = append(matched, NgramShare{seq, val, share(val, totals[category.n])})
+				count += val
+			}
+		}
+
+		slices.SortFunc(matched, func(a, b NgramShare) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Ngram, b.Ngram))
+		})
+		report.Categories = append(report.Categories, CategoryReport{
+			Name:  category.name,
+			Total: share(count, totals[category.n]),
+			Top:   matched[:min(top, len(matched))],
+		})
+	}
+
+	sfbs := make([]int, len(kb.Groups))
+	for seq, val := range strokes.Bigrams {
+		if isSfb(kb, seq) {
+			sfbs[kb.GroupId[[]rune(seq)[0]]] += val
+		}
+	}
+	for i, usage := range FingerUsage(kb, cf) {
+		report.Fingers = append(report.Fingers, FingerReport{
+			Finger: FingerNames[i],
+			Keys:   kb.Groups[i],
+			Usage:  usage,
+			Sfb:    share(sfbs[i], totals[2]),
+		})
+	}
+
+	return report
+}
+
+// Writes the report as tables
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Analysis of %s\n", r.Layout)
+	for _, category := range r.Categories {
+		b.WriteString(strings.Repeat("-", 65) + "\n")
+		fmt.Fprintf(&b, "%-12s %.2f%%\n", category.Name, category.Total)
+		for _, ngram := range category.Top {
+			fmt.Fprintf(&b, "  %-10s %-10d %.3f%%\n", ngram.Ngram, ngram.Count, ngram.Share)
+		}
+	}
+
+	b.WriteString(strings.Repeat("-", 65) + "\n")
+	fmt.Fprintf(&b, "%-8s %-10s %-8s %-8s\n", "Finger", "Keys", "Usage", "SFB")
+	for _, finger := range r.Fingers {
+		fmt.Fprintf(&b, "%-8s %-10s %-8.2f %-8.3f\n", finger.Finger, finger.Keys, finger.Usage, finger.Sfb)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	kbd "kbannealing/keyboard"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+
+	tests := []struct {
+		match func(*kbd.Keyboard, string) bool
+		name  string
+		yes   []string
+		no    []string
+	}{
+		{isSfb, "isSfb", []string{"ed", "ju", "ki"}, []string{"ee", "er", "ej"}},
+		{isScissor, "isScissor", []string{"ex", "cr", "xe", ",o"}, []string{"ec", "er", "ew", "eu"}},
+		{isRedirect, "isRedirect", []string{"sad", "ads", "kjl", "rwe"}, []string{"asd", "dsa", "aed", "sas", "sak"}},
+		{isAlternate, "isAlternate", []string{"aja", "jak", "the"}, []string{"asd", "ajj"}},
+	}
+
+	for _, test := range tests {
+		for _, ngram := range test.yes {
+			if !test.match(kb, ngram) {
+				t.Errorf("%s(%q) = false but want true", test.name, ngram)
+			}
+		}
+		for _, ngram := range test.no {
+			if test.match(kb, ngram) {
+				t.Errorf("%s(%q) = true but want false", test.name, ngram)
+			}
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Analyze("qwerty", kb, cf, 5)
+
+	// totals match the scores of the metrics
+	scores := map[string]int{
+		"sfb":       SfbScore(kb, cf),
+		"scissor":   ScissorScore(kb, cf),
+		"redirect":  RedirectScore(kb, cf),
+		"alternate": AlternateScore(kb, cf),
+	}
+	totals := map[string]int{"sfb": sum(cf.Bigrams), "scissor": sum(cf.Bigrams), "redirect": sum(cf.Trigrams), "alternate": sum(cf.Trigrams)}
+	for _, category := range report.Categories {
+		want := share(scores[category.Name], totals[category.Name])
+		if math.Abs(category.Total-want) > 1e-9 {
+			t.Errorf("Analyze() %s total = %f but want %f", category.Name, category.Total, want)
+		}
+		if len(category.Top) != 5 {
+			t.Errorf("Analyze() %s has %d n-grams but want 5", category.Name, len(category.Top))
+		}
+		for i := 1; i < len(category.Top); i++ {
+			if category.Top[i].Count > category.Top[i-1].Count {
+				t.Errorf("Analyze() %s is not sorted by count", category.Name)
+			}
+		}
+	}
+
+	sfb := 0.0
+	for _, finger := range report.Fingers {
+		sfb += finger.Sfb
+	}
+	if math.Abs(sfb-report.Categories[0].Total) > 1e-9 {
+		t.Errorf("Analyze() finger sfb sums to %f but want %f", sfb, report.Categories[0].Total)
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), report.Categories[0].Top[0].Ngram) {
+		t.Errorf("WriteText() does not list the top sfb %s", report.Categories[0].Top[0].Ngram)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := Report{}
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Layout != "qwerty" || len(decoded.Fingers) != 8 {
+		t.Errorf("Analyze() report does not round trip through JSON: %s", data)
+	}
+}
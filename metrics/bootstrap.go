@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// Resampling of the frequency data, to tell whether differences of stats between layouts are
+// larger than the noise of the corpus. Every n-gram count is redrawn from a Poisson distribution
+// with the count as its mean, which treats n-grams as independent. N-grams of a word are not,
+// so the intervals are somewhat narrower than those of resampling whole texts. Counts that are
+// scaled, like those of blends, are only as noisy as the text they came from, so they are scaled
+// back to the size of that text first.
+
+type BootstrapOptions struct {
+	// Number of resampled corpora
+	Samples int
+	// Share of the resampled values within an interval, ex. 0.95
+	Confidence float64
+	// Seed of the random numbers, so a bootstrap can be repeated
+	Seed int64
+	// Number of characters of the text the frequency data was counted from, 0 if the counts
+	// are the counts of the text
+	Size int
+}
+
+// A stat on the whole frequency data and the bounds of its confidence interval
+type Interval struct {
+	Value float64
+	Low   float64
+	High  float64
+}
+
+func (i Interval) Contains(x float64) bool {
+	return i.Low <= x && x <= i.High
+}
+
+// Values of the stats on every resampled corpus
+type BootstrapResult struct {
+	Confidence float64
+	values     map[string]map[string]float64
+	samples    map[string]map[string][]float64
+}
+
+// Draws a Poisson distributed count with the given mean, approximated by a normal distribution
+// for large means
+func poisson(rng *rand.Rand, mean float64) int {
+	if mean > 30 {
+		return max(0, int(math.Round(mean+math.Sqrt(mean)*rng.NormFloat64())))
+	}
+
+	limit := math.Exp(-mean)
+	count := 0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		count++
+	}
+	return count
+}
+
+func resampleCounts[K comparable](counts map[K]int, scale float64, rng *rand.Rand) map[K]int {
+	out := make(map[K]int, len(counts))
+	for ngram, count := range counts {
+		if n := poisson(rng, float64(count)*scale); n > 0 {
+			out[ngram] = n
+		}
+	}
+	return out
+}
+
+// Returns frequency data with every count redrawn around the count of cf. If size is not 0, the
+// counts are scaled to a text of size characters first.
+func Resample(cf *kbd.CharFreq, size int, rng *rand.Rand) *kbd.CharFreq {
+	scale := 1.0
+	chars := 0
+	for _, count := range cf.Chars {
+		chars += count
+	}
+	if size > 0 && chars > 0 {
+		scale = float64(size) / float64(chars)
+	}
+
+	return &kbd.CharFreq{
+		Chars:     resampleCounts(cf.Chars, scale, rng),
+		Bigrams:   resampleCounts(cf.Bigrams, scale, rng),
+		Trigrams:  resampleCounts(cf.Trigrams, scale, rng),
+		Quadgrams: resampleCounts(cf.Quadgrams, scale, rng),
+	}
+}
+
+// Computes the stats of every keyboard on the frequency data and on opts.Samples resampled
+// corpora. All keyboards are scored on the same corpora, so their differences can be compared.
+func Bootstrap(keyboards map[string]*kbd.Keyboard, cf *kbd.CharFreq, stats []Descriptor, opts BootstrapOptions) *BootstrapResult {
+	r := &BootstrapResult{
+		Confidence: opts.Confidence,
+		values:     map[string]map[string]float64{},
+		samples:    map[string]map[string][]float64{},
+	}
+	for name, kb := range keyboards {
+		r.values[name] = map[string]float64{}
+		r.samples[name] = map[string][]float64{}
+		for _, d := range stats {
+			r.values[name][d.Name] = d.Normalize(d.Metric(kb, cf), cf)
+			r.samples[name][d.Name] = make([]float64, opts.Samples)
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), opts.Samples); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// every sample has its own seed, so results don't depend on the order of the workers
+				sample := Resample(cf, opts.Size, rand.New(rand.NewSource(opts.Seed+int64(i))))
+				for name, kb := range keyboards {
+					// a copy of the keyboard, so its keystroke cache does not keep every sample
+					kb = kb.WithLayout(kb.Layout)
+					for _, d := range stats {
+						r.samples[name][d.Name][i] = d.Normalize(d.Metric(kb, sample), sample)
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < opts.Samples; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return r
+}
+
+// Returns the value at quantile q of sorted values, interpolating between neighbours
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func (r *BootstrapResult) interval(value float64, samples []float64) Interval {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	tail := (1 - r.Confidence) / 2
+	return Interval{value, quantile(sorted, tail), quantile(sorted, 1-tail)}
+}
+
+// Confidence interval of a stat of a layout
+func (r *BootstrapResult) Interval(layout string, stat string) Interval {
+	return r.interval(r.values[layout][stat], r.samples[layout][stat])
+}
+
+// Confidence interval of the difference of a stat between layouts a and b, from the
+// differences on each resampled corpus
+func (r *BootstrapResult) Difference(a string, b string, stat string) Interval {
+	as, bs := r.samples[a][stat], r.samples[b][stat]
+	diffs := make([]float64, len(as))
+	for i := range diffs {
+		diffs[i] = as[i] - bs[i]
+	}
+	return r.interval(r.values[a][stat]-r.values[b][stat], diffs)
+}
+
+// Whether a stat of layouts a and b differs at the confidence level, that is the interval
+// of their difference does not contain 0
+func (r *BootstrapResult) Significant(a string, b string, stat string) bool {
+	return !r.Difference(a, b, stat).Contains(0)
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPoisson(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, mean := range []float64{0.5, 4, 1000} {
+		total := 0
+		n := 20000
+		for i := 0; i < n; i++ {
+			total += poisson(rng, mean)
+		}
+		if got := float64(total) / float64(n); math.Abs(got-mean) > mean*0.05 {
+			t.Errorf("poisson(%v) mean = %v but want %v", mean, got, mean)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	for _, test := range []struct{ q, want float64 }{{0, 1}, {0.5, 3}, {0.125, 1.5}, {1, 5}} {
+		if got := quantile(sorted, test.q); got != test.want {
+			t.Errorf("quantile(%v) = %v but want %v", test.q, got, test.want)
+		}
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyboards := map[string]*kbd.Keyboard{
+		"qwerty":     kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./"),
+		"qwerty too": kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./"),
+		"colemak":    kbd.NewKeyboard("qwfpgjluy;arstdhneio'zxcvbkm,./"),
+	}
+	sfb, _ := Lookup("sfb")
+
+	result := Bootstrap(keyboards, cf, []Descriptor{sfb}, BootstrapOptions{Samples: 30, Confidence: 0.95, Seed: 1})
+
+	interval := result.Interval("qwerty", "sfb")
+	if want := sfb.Normalize(SfbScore(keyboards["qwerty"], cf), cf); interval.Value != want || !interval.Contains(want) || interval.Low >= interval.High {
+		t.Errorf("Interval(qwerty, sfb) = %+v but want around %v", interval, want)
+	}
+
+	if result.Significant("qwerty", "qwerty too", "sfb") {
+		t.Errorf("Significant() of the same layout = true but want false")
+	}
+	if !result.Significant("qwerty", "colemak", "sfb") {
+		t.Errorf("Significant(qwerty, colemak) = false but want true")
+	}
+	if diff := result.Difference("qwerty", "colemak", "sfb"); diff.Low <= 0 {
+		t.Errorf("Difference(qwerty, colemak) = %+v but want qwerty to have more sfbs", diff)
+	}
+}
+
+func TestBootstrapSize(t *testing.T) {
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyboards := map[string]*kbd.Keyboard{"qwerty": kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")}
+	sfb, _ := Lookup("sfb")
+
+	// the same frequencies from a text a hundredth of the size are noisier
+	width := func(size int) float64 {
+		result := Bootstrap(keyboards, cf, []Descriptor{sfb}, BootstrapOptions{Samples: 30, Confidence: 0.95, Seed: 1, Size: size})
+		interval := result.Interval("qwerty", "sfb")
+		return interval.High - interval.Low
+	}
+	observed, small := width(0), width(10000)
+	if small < observed*5 {
+		t.Errorf("Bootstrap() interval width of 10000 characters = %v but want much wider than %v", small, observed)
+	}
+
+	// scaling to the size of the text leaves the counts as they are on average
+	chars := 0
+	for _, count := range cf.Chars {
+		chars += count
+	}
+	sample := Resample(cf, chars*1000, rand.New(rand.NewSource(1)))
+	if got, want := float64(sample.Chars['e'])/1000, float64(cf.Chars['e']); math.Abs(got-want) > want*0.01 {
+		t.Errorf("Resample() of 1000 times the size has %v e's but want about %v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"fmt"
+	kbd "kbannealing/keyboard"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Short names of the fingers, in the same order as kb.Groups
+var FingerNames = kbd.FingerNames
+
+// Percentage of keystrokes each finger should do, in the same order as kb.Groups.
+// Targets with 8 values leave out the thumbs.
+type LoadTarget []float64
+
+var DefaultLoadTarget = LoadTarget{8, 11, 16, 15, 15, 16, 11, 8}
+
+// Parses a comma separated list of percentages, ex. "8,11,16,15,15,16,11,8"
+// Two more values can be given for the left and right thumb.
+// The values are rescaled so they sum up to 100.
+func ParseLoadTarget(s string) (LoadTarget, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 8 && len(parts) != len(FingerNames) {
+		return nil, fmt.Errorf("load target needs 8 or %d values, got %d", len(FingerNames), len(parts))
+	}
+
+	target := make(LoadTarget, len(parts))
+	sum := 0.0
+	for i, part := range parts {
+		val, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid load target value %q: %w", part, err)
+		}
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return nil, fmt.Errorf("load target value %q is not a number", part)
+		}
+		if val < 0 {
+			return nil, fmt.Errorf("load target value %q is negative", part)
+		}
+		target[i] = val
+		sum += val
+	}
+
+	if sum == 0 {
+		return nil, fmt.Errorf("load target values sum up to 0")
+	}
+	for i := range target {
+		target[i] = target[i] / sum * 100
+	}
+
+	return target, nil
+}
+
+// Percentage of the characters on the keyboard typed by each finger, in the same order as kb.Groups
+func FingerUsage(kb *kbd.Keyboard, cf *kbd.CharFreq) []float64 {
+	cf = kb.Keystrokes(cf)
+	usage := make([]float64, len(kb.Groups))
+	total := 0
+
+	for i := range kb.Groups {
+		for _, c := range kb.Groups[i] {
+			usage[i] += float64(cf.Chars[c])
+			total += cf.Chars[c]
+		}
+	}
+
+	if total == 0 {
+		return usage
+	}
+	for i := range usage {
+		usage[i] = usage[i] / float64(total) * 100
+	}
+
+	return usage
+}
+
+// Percentage of the characters on the keyboard typed by each hand
+func HandUsage(kb *kbd.Keyboard, cf *kbd.CharFreq) (left float64, right float64) {
+	for i, val := range FingerUsage(kb, cf) {
+		if kbd.FingerOnLeft(i) {
+			left += val
+		} else {
+			right += val
+		}
+	}
+
+	return left, right
+}
+
+// Creates a metric that sums up how far each finger is from its target usage.
+// Fingers without a target are left out, and the usage of the others is rescaled to sum up to 100.
+// The score is in hundredths of a percent, lower is better.
+func LoadDeviation(target LoadTarget) Metric {
+	return func(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+		usage := FingerUsage(kb, cf)
+		usage = usage[:min(len(usage), len(target))]
+
+		sum := 0.0
+		for _, val := range usage {
+			sum += val
+		}
+		if sum == 0 {
+			return 0
+		}
+
+		deviation := 0.0
+		for i, val := range usage {
+			deviation += math.Abs(val/sum*100 - target[i])
+		}
+		return int(math.Round(deviation * 100))
+	}
+}
+
+// Finger load deviation from DefaultLoadTarget
+func LoadScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	return LoadDeviation(DefaultLoadTarget)(kb, cf)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"testing"
+)
+
+func TestFingerUsage(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Error(err)
+	}
+
+	usage := FingerUsage(kb, cf)
+	sum := 0.0
+	for _, val := range usage {
+		sum += val
+	}
+	if math.Abs(sum-100) > 1e-9 {
+		t.Errorf("FingerUsage() sums to %f but want 100", sum)
+	}
+
+	left, right := HandUsage(kb, cf)
+	if math.Abs(left+right-100) > 1e-9 {
+		t.Errorf("HandUsage() = %f, %f but want a sum of 100", left, right)
+	}
+
+	// qwerty is known to be left heavy
+	if left <= right {
+		t.Errorf("HandUsage() = %f, %f but want left > right", left, right)
+	}
+
+	if score := LoadDeviation(LoadTarget(usage))(kb, cf); score != 0 {
+		t.Errorf("LoadDeviation() of its own usage = %d but want 0", score)
+	}
+}
+
+func TestParseLoadTarget(t *testing.T) {
+	target, err := ParseLoadTarget("1,1,2,2,2,2,1,1")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if math.Abs(target[0]-100.0/12) > 1e-9 || math.Abs(target[2]-200.0/12) > 1e-9 {
+		t.Errorf("ParseLoadTarget() = %v, values are not rescaled", target)
+	}
+
+	for _, s := range []string{"1,2,3", "a,1,1,1,1,1,1,1", "0,0,0,0,0,0,0,0", "-1,1,1,1,1,1,1,1",
+		"NaN,1,1,1,1,1,1,1", "Inf,1,1,1,1,1,1,1"} {
+		if _, err := ParseLoadTarget(s); err == nil {
+			t.Errorf("ParseLoadTarget(%q) should fail", s)
+		}
+	}
+}
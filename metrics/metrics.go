@@ -49,6 +49,7 @@ func stringProduct(strings ...string) chan string {
 }
 
 func AlternateScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	cf = kb.Keystrokes(cf)
 	score := 0
 
 	for seq := range stringProduct(kb.Left, kb.Right, kb.Left) {
@@ -70,11 +71,13 @@ func AlternateScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
 
 // Single Finger Bigrams
 func SfbScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	cf = kb.Keystrokes(cf)
 	score := 0
 
 	for _, group := range kb.Groups {
 		for seq := range stringProduct(group, group) {
-			if seq[0] == seq[1] {
+			runes := []rune(seq)
+			if runes[0] == runes[1] {
 				continue
 			}
 			val, ok := cf.Bigrams[seq]
@@ -89,6 +92,7 @@ func SfbScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
 
 // 2 Roll + Alternate Hand
 func RollScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	cf = kb.Keystrokes(cf)
 	score := 0
 	for seq := range stringProduct(kb.Left, kb.Left, kb.Right) {
 		val, ok := cf.Trigrams[seq]
@@ -116,6 +120,7 @@ func RollScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
 
 // Same side of keyboard, in the same direction. Ex: "lkj" or "jkl"
 func ThreeRollScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	cf = kb.Keystrokes(cf)
 	score := 0
 
 	// Left groups, indexes 0 - 3
@@ -140,12 +145,3 @@ func ThreeRollScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
 
 	return score
 }
-
-func AllMetrics(kb *kbd.Keyboard, cf *kbd.CharFreq) map[string]int {
-	return map[string]int{
-		"alternate": AlternateScore(kb, cf),
-		"sfb":       SfbScore(kb, cf),
-		"roll":      RollScore(kb, cf),
-		"3roll":     ThreeRollScore(kb, cf),
-	}
-}
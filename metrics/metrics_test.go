@@ -43,3 +43,67 @@ func TestStringProduct(t *testing.T) {
 		}
 	}
 }
+
+func TestUnicodeSfb(t *testing.T) {
+	// ä and z share the left pinky, ö and ø share the right pinky
+	kb := kbd.NewKeyboard("qwertyuiopäsdfghjklöøzxcvbnm,.-")
+	cf := &kbd.CharFreq{
+		Chars:    map[rune]int{'ä': 1, 'z': 1, 'ö': 1, 'ø': 1},
+		Bigrams:  map[string]int{"äz": 3, "öø": 2, "ää": 5, "äö": 7},
+		Trigrams: map[string]int{},
+	}
+
+	if score := SfbScore(kb, cf); score != 5 {
+		t.Errorf("SfbScore() = %d but want 5", score)
+	}
+}
+
+func TestSpaceThumb(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	cf := &kbd.CharFreq{
+		Chars:    map[rune]int{'a': 2, 'b': 1, ' ': 1},
+		Bigrams:  map[string]int{"a ": 1, " b": 1},
+		Trigrams: map[string]int{"a b": 4},
+	}
+
+	if score := AlternateScore(kb, cf); score != 0 {
+		t.Errorf("AlternateScore() without thumbs = %d but want 0", score)
+	}
+
+	// space on the right thumb alternates with the left hand
+	if score := AlternateScore(kb.WithThumbs(string(kbd.Blank)+" "), cf); score != 4 {
+		t.Errorf("AlternateScore() = %d but want 4", score)
+	}
+
+	// space on the left thumb is a separate finger, so it is no same finger bigram, and "a b" is
+	// typed by the left hand alone, which is no roll
+	leftSpace := kb.WithThumbs(" " + string(kbd.Blank))
+	if score := SfbScore(leftSpace, cf); score != 0 {
+		t.Errorf("SfbScore() = %d but want 0", score)
+	}
+	if score := RollScore(leftSpace, cf); score != 0 {
+		t.Errorf("RollScore() = %d but want 0", score)
+	}
+}
+
+func TestQuadgramMetrics(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	cf := &kbd.CharFreq{
+		Chars:     map[rune]int{},
+		Bigrams:   map[string]int{},
+		Quadgrams: map[string]int{"sdjk": 5, "were": 3, "frjk": 2, "sdkj": 7, "ffjk": 1, "sjdk": 4},
+	}
+
+	// "sdjk" and "sdkj" roll on both hands, "frjk" is a same finger bigram on the left index
+	if score := ChainedRollScore(kb, cf); score != 12 {
+		t.Errorf("ChainedRollScore() = %d but want 12", score)
+	}
+
+	if score := SameHandFourScore(kb, cf); score != 3 {
+		t.Errorf("SameHandFourScore() = %d but want 3", score)
+	}
+
+	if score := ChainedRollScore(kb, &kbd.CharFreq{}); score != 0 {
+		t.Errorf("ChainedRollScore() without quadgrams = %d but want 0", score)
+	}
+}
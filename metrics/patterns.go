@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+)
+
+// Two different keys typed by the same finger
+func isSfb(kb *kbd.Keyboard, bigram string) bool {
+	runes := []rune(bigram)
+	f, ok := fingers(kb, bigram)
+	return ok && len(f) == 2 && f[0] == f[1] && runes[0] != runes[1]
+}
+
+// Two keys typed by adjacent fingers of one hand that are two rows apart. Ex: "ex" or "cr" on qwerty
+func isScissor(kb *kbd.Keyboard, bigram string) bool {
+	f, ok := fingers(kb, bigram)
+	if !ok || len(f) != 2 || f[0] >= kbd.LeftThumb || f[1] >= kbd.LeftThumb {
+		return false
+	}
+	if f[0]-f[1] != 1 && f[1]-f[0] != 1 || kbd.FingerOnLeft(f[0]) != kbd.FingerOnLeft(f[1]) {
+		return false
+	}
+
+	runes := []rune(bigram)
+	a, okA := kb.Position(runes[0])
+	b, okB := kb.Position(runes[1])
+	return okA && okB && math.Abs(a.Y-b.Y) >= 2
+}
+
+// Three keys typed by different fingers of one hand that change direction. Ex: "sad" or "ads" on qwerty
+func isRedirect(kb *kbd.Keyboard, trigram string) bool {
+	f, ok := fingers(kb, trigram)
+	if !ok || len(f) != 3 {
+		return false
+	}
+	for _, finger := range f {
+		if finger >= kbd.LeftThumb || kbd.FingerOnLeft(finger) != kbd.FingerOnLeft(f[0]) {
+			return false
+		}
+	}
+	if f[0] == f[1] || f[1] == f[2] || f[0] == f[2] {
+		return false
+	}
+	return (f[1] > f[0]) != (f[2] > f[1])
+}
+
+// Three keys typed by alternating hands, the same pattern as AlternateScore
+func isAlternate(kb *kbd.Keyboard, trigram string) bool {
+	f, ok := fingers(kb, trigram)
+	if !ok || len(f) != 3 {
+		return false
+	}
+	left := kbd.FingerOnLeft(f[1])
+	return kbd.FingerOnLeft(f[0]) != left && kbd.FingerOnLeft(f[2]) != left
+}
+
+func countMatching(kb *kbd.Keyboard, ngrams map[string]int, match func(*kbd.Keyboard, string) bool) int {
+	score := 0
+	for seq, val := range ngrams {
+		if match(kb, seq) {
+			score += val
+		}
+	}
+	return score
+}
+
+// One handed trigrams that change direction, lower is better
+func RedirectScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	return countMatching(kb, kb.Keystrokes(cf).Trigrams, isRedirect)
+}
+
+// Bigrams typed by adjacent fingers jumping between the top and bottom row, lower is better
+func ScissorScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	return countMatching(kb, kb.Keystrokes(cf).Bigrams, isScissor)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+)
+
+// Looks up the fingers of the keys of an n-gram, false if a key is not on the keyboard
+func fingers(kb *kbd.Keyboard, ngram string) ([]int, bool) {
+	out := make([]int, 0, 4)
+	for _, c := range ngram {
+		finger, ok := kb.GroupId[c]
+		if !ok {
+			return nil, false
+		}
+		out = append(out, finger)
+	}
+	return out, true
+}
+
+// Two keys typed by different fingers of the same hand
+func isRoll(a int, b int) bool {
+	return a != b && kbd.FingerOnLeft(a) == kbd.FingerOnLeft(b)
+}
+
+// Two consecutive rolls, one on each hand. Ex: "ster" or "ngth"
+// Needs quadgrams, the score is 0 if cf has none.
+func ChainedRollScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	cf = kb.Keystrokes(cf)
+	score := 0
+
+	for seq, val := range cf.Quadgrams {
+		f, ok := fingers(kb, seq)
+		if !ok || len(f) != 4 {
+			continue
+		}
+
+		if isRoll(f[0], f[1]) && isRoll(f[2], f[3]) && kbd.FingerOnLeft(f[1]) != kbd.FingerOnLeft(f[2]) {
+			score += val
+		}
+	}
+
+	return score
+}
+
+// Four keys in a row typed by the same hand. Ex: "were" on qwerty
+// Needs quadgrams, the score is 0 if cf has none.
+func SameHandFourScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	cf = kb.Keystrokes(cf)
+	score := 0
+
+	for seq, val := range cf.Quadgrams {
+		f, ok := fingers(kb, seq)
+		if !ok || len(f) != 4 {
+			continue
+		}
+
+		left := kbd.FingerOnLeft(f[0])
+		if kbd.FingerOnLeft(f[1]) == left && kbd.FingerOnLeft(f[2]) == left && kbd.FingerOnLeft(f[3]) == left {
+			score += val
+		}
+	}
+
+	return score
+}
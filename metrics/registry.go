@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	kbd "kbannealing/keyboard"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// How the score of a metric is turned into the value reported in the stats
+type Normalization int
+
+const (
+	// Percentage of all n-grams of the order of the metric in the frequency data
+	PercentOfNgrams Normalization = iota
+	// Percentages in hundredths, like LoadDeviation and weighted sums
+	HundredthsOfPercent
+	// Scores in hundredths of another unit, like TravelMetric in hundredths of a key width
+	Hundredths
+	// Scores without a unit, like weighted sums, are reported as is
+	Raw
+)
+
+// Describes a metric, so the stats and the annealer can use it by name
+type Descriptor struct {
+	Name        string
+	Description string
+	Metric      Metric
+	// Length of the n-grams the metric counts, 0 for metrics that are not based on n-grams
+	Order         int
+	LowerIsBetter bool
+	Normalization Normalization
+	// Metrics depending on the order of the fingers, like 3roll, keep the columns in place
+	// when the homerow is optimized
+	LockColumns bool
+	// Metrics depending on the rows of the keys, like scissor, are not passed to OptimizeHomerow,
+	// which moves keys between the rows of a column
+	RowDependent bool
+	// Metrics that are part of AllMetrics and the stats table
+	Stat bool
+}
+
+// Maximizes alternate + roll - sfb
+func CombinedScore(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+	return -SfbScore(kb, cf) + AlternateScore(kb, cf) + RollScore(kb, cf)
+}
+
+var registry = []Descriptor{
+	{
+		Name: "alternate", Description: "Trigrams alternating between the hands", Metric: AlternateScore,
+		Order: 3, Stat: true,
+	},
+	{
+		Name: "roll", Description: "Trigrams of two keys on one hand and one on the other", Metric: RollScore,
+		Order: 3, Stat: true,
+	},
+	{
+		Name: "sfb", Description: "Bigrams of different keys typed by the same finger", Metric: SfbScore,
+		Order: 2, LowerIsBetter: true, Stat: true,
+	},
+	{
+		Name: "3roll", Description: "Trigrams rolling over three fingers of one hand in one direction", Metric: ThreeRollScore,
+		Order: 3, LockColumns: true, Stat: true,
+	},
+	{
+		Name: "combined", Description: "Alternate + roll - sfb, in n-grams", Metric: CombinedScore,
+		Order: 3, Normalization: Raw,
+	},
+	{
+		Name: "redirect", Description: "One handed trigrams that change direction", Metric: RedirectScore,
+		Order: 3, LowerIsBetter: true, LockColumns: true,
+	},
+	{
+		Name: "scissor", Description: "Bigrams of adjacent fingers jumping between the top and bottom row", Metric: ScissorScore,
+		Order: 2, LowerIsBetter: true, LockColumns: true, RowDependent: true,
+	},
+	{
+		Name: "load", Description: "Deviation of the finger usage from the default target, in percent", Metric: LoadScore,
+		LowerIsBetter: true, Normalization: HundredthsOfPercent, LockColumns: true,
+	},
+	{
+		Name: "chainedroll", Description: "Quadgrams of a roll on one hand followed by a roll on the other", Metric: ChainedRollScore,
+		Order: 4, Stat: true,
+	},
+	{
+		Name: "samehand4", Description: "Quadgrams typed by one hand", Metric: SameHandFourScore,
+		Order: 4, LowerIsBetter: true, Stat: true,
+	},
+}
+
+// Metrics optimized when none are chosen
+var DefaultObjectives = []string{"sfb", "alternate", "roll", "3roll", "combined"}
+
+// Returns all registered metrics
+func Registry() []Descriptor {
+	return slices.Clone(registry)
+}
+
+// Adds a metric, replacing a registered metric of the same name
+func Register(d Descriptor) {
+	idx := slices.IndexFunc(registry, func(other Descriptor) bool { return other.Name == d.Name })
+	if idx == -1 {
+		registry = append(registry, d)
+	} else {
+		registry[idx] = d
+	}
+}
+
+func registeredNames() string {
+	names := make([]string, len(registry))
+	for i, d := range registry {
+		names[i] = d.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func Lookup(name string) (Descriptor, error) {
+	for _, d := range registry {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return Descriptor{}, fmt.Errorf("unknown metric %q, use one of %s", name, registeredNames())
+}
+
+// Parses a comma separated list of metric names, ex. "sfb,roll"
+func ParseMetrics(s string) ([]Descriptor, error) {
+	out := []Descriptor{}
+	for _, name := range strings.Split(s, ",") {
+		d, err := Lookup(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Returns the n-grams of the order of a metric, nil for metrics not based on n-grams
+func ngramsOf(cf *kbd.CharFreq, order int) map[string]int {
+	switch order {
+	case 2:
+		return cf.Bigrams
+	case 3:
+		return cf.Trigrams
+	case 4:
+		return cf.Quadgrams
+	}
+	return nil
+}
+
+// Metrics based on n-grams need n-grams of their order, ex. quadgram metrics need quadgrams
+func (d Descriptor) Available(cf *kbd.CharFreq) bool {
+	return d.Order < 2 || len(ngramsOf(cf, d.Order)) > 0
+}
+
+// Turns a score of the metric into the value reported in the stats
+func (d Descriptor) Normalize(score int, cf *kbd.CharFreq) float64 {
+	switch d.Normalization {
+	case PercentOfNgrams:
+		return share(score, sum(ngramsOf(cf, d.Order)))
+	case HundredthsOfPercent, Hundredths:
+		return float64(score) / 100
+	}
+	return float64(score)
+}
+
+// Number of n-grams a point of the score stands for, or keys pressed for metrics not based on
+// n-grams. The annealer scales the differences between scores by it, so one temperature suits
+// metrics of all units.
+func (d Descriptor) NgramsPerPoint(cf *kbd.CharFreq) float64 {
+	switch d.Normalization {
+	case HundredthsOfPercent:
+		total := sum(ngramsOf(cf, d.Order))
+		if d.Order < 2 {
+			for _, val := range cf.Chars {
+				total += val
+			}
+		}
+		return float64(total) / 10000
+	case Hundredths:
+		// a key width of travel is about one key pressed
+		return 0.01
+	}
+	return 1
+}
+
+// Scores of the stat metrics, leaving out metrics without n-grams of their order in cf
+func AllMetrics(kb *kbd.Keyboard, cf *kbd.CharFreq) map[string]int {
+	scores := map[string]int{}
+	for _, d := range registry {
+		if d.Stat && d.Available(cf) {
+			scores[d.Name] = d.Metric(kb, cf)
+		}
+	}
+	return scores
+}
+
+// Normalized values of the stat metrics, leaving out metrics without n-grams of their order in cf
+func Stats(kb *kbd.Keyboard, cf *kbd.CharFreq) map[string]float64 {
+	stats := map[string]float64{}
+	for name, score := range AllMetrics(kb, cf) {
+		d, _ := Lookup(name)
+		stats[name] = d.Normalize(score, cf)
+	}
+	return stats
+}
+
+// Creates a metric maximizing the sum of registered metrics multiplied by their weight. The weights
+// apply to the values reported in the stats, so metrics counting different n-grams add up in the
+// same unit, and the sum is scored in hundredths. Metrics where lower is better need a negative
+// weight to be minimized.
+func Weighted(name string, weights map[string]float64) (Descriptor, error) {
+	if len(weights) == 0 {
+		return Descriptor{}, fmt.Errorf("metric %q has no weights", name)
+	}
+
+	type term struct {
+		descriptor Descriptor
+		weight     float64
+	}
+	terms := []term{}
+	parts := []string{}
+	order := 0
+	lockColumns, rowDependent := false, false
+	for _, metricName := range sortedKeys(weights) {
+		d, err := Lookup(metricName)
+		if err != nil {
+			return Descriptor{}, fmt.Errorf("metric %q: %w", name, err)
+		}
+		if d.Normalization == Raw {
+			return Descriptor{}, fmt.Errorf("metric %q: %s has no unit to be weighted in, weight its parts instead", name, metricName)
+		}
+		terms = append(terms, term{d, weights[metricName]})
+		parts = append(parts, fmt.Sprintf("%g %s", weights[metricName], metricName))
+		order = max(order, d.Order)
+		lockColumns = lockColumns || d.LockColumns
+		rowDependent = rowDependent || d.RowDependent
+	}
+
+	metric := func(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+		score := 0.0
+		for _, t := range terms {
+			score += t.weight * t.descriptor.Normalize(t.descriptor.Metric(kb, cf), cf)
+		}
+		return int(math.Round(score * 100))
+	}
+	return Descriptor{
+		Name:          name,
+		Description:   "Weighted sum of " + strings.Join(parts, ", "),
+		Metric:        metric,
+		Order:         order,
+		Normalization: HundredthsOfPercent,
+		LockColumns:   lockColumns,
+		RowDependent:  rowDependent,
+	}, nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Reads weighted metrics from a JSON scoring file, mapping the name of each metric to the
+// weights of the registered metrics it sums up, ex. {"balanced": {"sfb": -2, "roll": 1}}
+func LoadScoring(path string) ([]Descriptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]map[string]float64{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid scoring file %s: %w", path, err)
+	}
+
+	out := []Descriptor{}
+	for _, name := range sortedKeys(config) {
+		d, err := Weighted(name, config[name])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMetrics(t *testing.T) {
+	metrics, err := ParseMetrics("sfb, 3roll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 2 || metrics[0].Name != "sfb" || !metrics[0].LowerIsBetter ||
+		metrics[1].Name != "3roll" || metrics[1].LowerIsBetter || !metrics[1].LockColumns {
+		t.Errorf("ParseMetrics() = %v but want sfb and 3roll", metrics)
+	}
+
+	if _, err := ParseMetrics("sfb,nope"); err == nil {
+		t.Errorf("ParseMetrics() of an unknown metric should fail")
+	}
+
+	for _, name := range DefaultObjectives {
+		if _, err := Lookup(name); err != nil {
+			t.Errorf("Lookup(%q) = %s", name, err)
+		}
+	}
+}
+
+func TestRowDependent(t *testing.T) {
+	for _, name := range DefaultObjectives {
+		if d, _ := Lookup(name); d.RowDependent {
+			t.Errorf("%s should not be row dependent", name)
+		}
+	}
+
+	d, err := Weighted("rows", map[string]float64{"roll": 1, "scissor": -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.RowDependent {
+		t.Errorf("Weighted() of scissor should be row dependent")
+	}
+}
+
+func TestLoadScoring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	config := `{"balanced": {"sfb": -1, "alternate": 1, "roll": 1}, "rolls": {"roll": 2, "3roll": 1}}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics, err := LoadScoring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 2 || metrics[0].Name != "balanced" || metrics[1].Name != "rolls" {
+		t.Fatalf("LoadScoring() = %v but want balanced and rolls", metrics)
+	}
+	if metrics[0].LockColumns || !metrics[1].LockColumns {
+		t.Errorf("LoadScoring() only rolls should lock columns, as it includes 3roll")
+	}
+
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the weights apply to the percentages of the stats, not the n-gram counts
+	stats := Stats(kb, cf)
+	want := int(math.Round((stats["alternate"] + stats["roll"] - stats["sfb"]) * 100))
+	if got := metrics[0].Metric(kb, cf); got != want {
+		t.Errorf("balanced metric = %d but want %d", got, want)
+	}
+	if got := metrics[0].Normalize(want, cf); got != float64(want)/100 {
+		t.Errorf("balanced Normalize() = %f but want %f", got, float64(want)/100)
+	}
+
+	for _, config := range []string{`{"bad": {"nope": 1}}`, `{"raw": {"combined": 1, "sfb": -1}}`} {
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScoring(path); err == nil {
+			t.Errorf("LoadScoring(%s) should fail", config)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// quadgram metrics are left out without quadgrams
+	scores := AllMetrics(kb, cf)
+	if _, ok := scores["chainedroll"]; ok || len(scores) != 4 {
+		t.Errorf("AllMetrics() = %v but want the 4 bigram and trigram stats", scores)
+	}
+
+	sfb, _ := Lookup("sfb")
+	if got, want := sfb.Normalize(scores["sfb"], cf), share(scores["sfb"], sum(cf.Bigrams)); got != want {
+		t.Errorf("sfb Normalize() = %f but want %f", got, want)
+	}
+	roll, _ := Lookup("roll")
+	if got, want := roll.Normalize(scores["roll"], cf), share(scores["roll"], sum(cf.Trigrams)); got != want {
+		t.Errorf("roll Normalize() = %f but want %f", got, want)
+	}
+	load, _ := Lookup("load")
+	if got := load.Normalize(1234, cf); got != 12.34 {
+		t.Errorf("load Normalize() = %f but want 12.34", got)
+	}
+
+	// a point of load is a hundredth of a percent of the keys pressed
+	chars := 0
+	for _, val := range cf.Chars {
+		chars += val
+	}
+	if got, want := load.NgramsPerPoint(cf), float64(chars)/10000; got != want {
+		t.Errorf("load NgramsPerPoint() = %f but want %f", got, want)
+	}
+	if got := sfb.NgramsPerPoint(cf); got != 1 {
+		t.Errorf("sfb NgramsPerPoint() = %f but want 1", got)
+	}
+	weighted, err := Weighted("balanced", map[string]float64{"sfb": -1, "roll": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := weighted.NgramsPerPoint(cf), float64(sum(cf.Trigrams))/10000; got != want {
+		t.Errorf("weighted NgramsPerPoint() = %f but want %f", got, want)
+	}
+}
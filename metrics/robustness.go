@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"slices"
+)
+
+// Stats of layouts on several corpora, to tell whether a layout only does well on the corpus
+// it was optimized for
+type CorpusMatrix struct {
+	Corpora []string
+	Layouts []string
+	Stats   []Descriptor
+	// Values by stat name and layout, one per corpus
+	Values map[string]map[string][]float64
+}
+
+// Scores every keyboard on every corpus, corpora and cfs are in the same order
+func ScoreCorpora(keyboards map[string]*kbd.Keyboard, corpora []string, cfs []*kbd.CharFreq, stats []Descriptor) *CorpusMatrix {
+	layouts := make([]string, 0, len(keyboards))
+	for name := range keyboards {
+		layouts = append(layouts, name)
+	}
+	slices.Sort(layouts)
+
+	m := &CorpusMatrix{corpora, layouts, stats, map[string]map[string][]float64{}}
+	for _, d := range stats {
+		m.Values[d.Name] = map[string][]float64{}
+		for _, name := range layouts {
+			values := make([]float64, len(cfs))
+			for i, cf := range cfs {
+				values[i] = d.Normalize(d.Metric(keyboards[name], cf), cf)
+			}
+			m.Values[d.Name][name] = values
+		}
+	}
+	return m
+}
+
+// Coefficient of variation in percent of a stat of a layout across corpora, relative to the
+// average layout on each corpus. Differences that every layout shares, such as a corpus with
+// more same finger bigrams overall, cancel out, so a layout that keeps its standing on every
+// corpus has a variation of 0. Corpora where the average layout has a value of 0 are skipped.
+func (m *CorpusMatrix) Variation(stat string, layout string) float64 {
+	relative := []float64{}
+	for i := range m.Corpora {
+		mean := 0.0
+		for _, name := range m.Layouts {
+			mean += m.Values[stat][name][i]
+		}
+		mean /= float64(len(m.Layouts))
+		if mean != 0 {
+			relative = append(relative, m.Values[stat][layout][i]/mean)
+		}
+	}
+	if len(relative) < 2 {
+		return 0
+	}
+
+	mean, variance := 0.0, 0.0
+	for _, r := range relative {
+		mean += r
+	}
+	mean /= float64(len(relative))
+	for _, r := range relative {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(relative))
+	if mean == 0 {
+		return 0
+	}
+	return math.Sqrt(variance) / math.Abs(mean) * 100
+}
+
+// Robustness score of a layout from 0 to 100: 100 minus its average variation over the stats.
+// 100 means the layout compares to the other layouts the same way on every corpus.
+func (m *CorpusMatrix) Robustness(layout string) float64 {
+	if len(m.Stats) == 0 {
+		return 100
+	}
+	total := 0.0
+	for _, d := range m.Stats {
+		total += m.Variation(d.Name, layout)
+	}
+	return max(0, 100-total/float64(len(m.Stats)))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"testing"
+)
+
+func TestVariation(t *testing.T) {
+	sfb, _ := Lookup("sfb")
+	matrix := &CorpusMatrix{
+		Corpora: []string{"a", "b"},
+		Layouts: []string{"steady", "steady too", "overfit"},
+		Stats:   []Descriptor{sfb},
+		Values: map[string]map[string][]float64{
+			// every layout doubles on the second corpus but overfit, which triples its value again
+			"sfb": {"steady": {1, 2}, "steady too": {1, 2}, "overfit": {1, 6}},
+		},
+	}
+
+	// relative to the average of 1 and 10/3, steady is at 1 and 0.6 and overfit at 1 and 1.8
+	if got := matrix.Variation("sfb", "steady"); math.Abs(got-25) > 1e-9 {
+		t.Errorf("Variation(steady) = %v but want 25", got)
+	}
+	if got := matrix.Variation("sfb", "overfit"); math.Abs(got-100.0*0.4/1.4) > 1e-9 {
+		t.Errorf("Variation(overfit) = %v but want %v", got, 100.0*0.4/1.4)
+	}
+	if matrix.Robustness("overfit") >= matrix.Robustness("steady") {
+		t.Errorf("Robustness(overfit) = %v but want less than %v", matrix.Robustness("overfit"), matrix.Robustness("steady"))
+	}
+
+	matrix.Layouts = []string{"steady", "overfit"}
+	matrix.Values["sfb"] = map[string][]float64{"steady": {1, 2}, "overfit": {3, 6}}
+	if got := matrix.Variation("sfb", "overfit"); got != 0 {
+		t.Errorf("Variation() of proportional values = %v but want 0", got)
+	}
+	if got := matrix.Robustness("steady"); got != 100 {
+		t.Errorf("Robustness() of proportional values = %v but want 100", got)
+	}
+}
+
+func TestScoreCorpora(t *testing.T) {
+	cf, err := kbd.CharFreqFromFolder("../CharFreqData/mt-quotes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyboards := map[string]*kbd.Keyboard{
+		"qwerty":  kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./"),
+		"colemak": kbd.NewKeyboard("qwfpgjluy;arstdhneio'zxcvbkm,./"),
+	}
+	sfb, _ := Lookup("sfb")
+
+	matrix := ScoreCorpora(keyboards, []string{"one", "two"}, []*kbd.CharFreq{cf, cf}, []Descriptor{sfb})
+	if len(matrix.Layouts) != 2 || matrix.Layouts[0] != "colemak" {
+		t.Errorf("ScoreCorpora() layouts = %v but want colemak, qwerty", matrix.Layouts)
+	}
+	want := sfb.Normalize(SfbScore(keyboards["qwerty"], cf), cf)
+	if values := matrix.Values["sfb"]["qwerty"]; len(values) != 2 || values[0] != want || values[1] != want {
+		t.Errorf("ScoreCorpora() qwerty sfb = %v but want %v twice", values, want)
+	}
+	if got := matrix.Robustness("qwerty"); got != 100 {
+		t.Errorf("Robustness() on the same corpus twice = %v but want 100", got)
+	}
+}
@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"fmt"
+	kbd "kbannealing/keyboard"
+	"math"
+	"os"
+	"unicode"
+)
+
+// Longest distance between two presses of the same finger that is tracked by Simulate
+const MaxSameFingerDistance = 3
+
+// Result of typing a text on a keyboard key by key
+type Simulation struct {
+	// Number of keys pressed, including layer and adaptive keys
+	Keys int
+	// Total distance travelled by all fingers, in key widths
+	Travel float64
+	// Distance travelled and keys pressed by each finger, in the same order as kb.Groups
+	FingerTravel  []float64
+	FingerPresses []int
+	// SameFinger[d] counts pairs of presses d keys apart that are typed by the same finger on
+	// different keys. SameFinger[1] are same finger bigrams, SameFinger[3] spans 4 keys.
+	SameFinger [MaxSameFingerDistance + 1]int
+}
+
+// Travel per key pressed
+func (s *Simulation) TravelPerKey() float64 {
+	if s.Keys == 0 {
+		return 0
+	}
+	return s.Travel / float64(s.Keys)
+}
+
+// Percentage of keys pressed by the same finger as the key d keys before it
+func (s *Simulation) SameFingerRate(d int) float64 {
+	if s.Keys == 0 {
+		return 0
+	}
+	return float64(s.SameFinger[d]) / float64(s.Keys) * 100
+}
+
+type press struct {
+	key    rune
+	finger int
+}
+
+// Replays a text on a keyboard key by key. Every finger starts on its home position and stays on the
+// last key it pressed, moving straight to the next key it presses. Characters are typed with their
+// layer and adaptive keys, characters that are not on the keyboard and whitespace without a space key
+// are skipped, and newlines start over from the home positions.
+func Simulate(kb *kbd.Keyboard, text []rune) *Simulation {
+	sim := &Simulation{
+		FingerTravel:  make([]float64, len(kb.Groups)),
+		FingerPresses: make([]int, len(kb.Groups)),
+	}
+
+	positions := make([]kbd.Point, len(kb.Groups))
+	recent := make([]press, 0, MaxSameFingerDistance)
+	var prev rune
+
+	home := func() {
+		for i := range positions {
+			positions[i] = kbd.HomePosition(i)
+		}
+		recent = recent[:0]
+		prev = 0
+	}
+	home()
+
+	type target struct {
+		pos    kbd.Point
+		finger int
+	}
+	targets := map[rune]target{}
+	lookup := func(key rune) (target, bool) {
+		t, ok := targets[key]
+		if ok {
+			return t, true
+		}
+
+		finger, onKeyboard := kb.GroupId[key]
+		pos, hasPos := kb.Position(key)
+		if !onKeyboard || !hasPos {
+			return t, false
+		}
+		t = target{pos, finger}
+		targets[key] = t
+		return t, true
+	}
+
+	for _, c := range text {
+		if c == '\n' {
+			home()
+			continue
+		}
+		if unicode.IsSpace(c) {
+			c = ' '
+		}
+
+		var keys []rune
+		if adaptive := kb.AdaptiveKey(prev, c); adaptive != c {
+			keys = []rune{adaptive}
+		} else {
+			keys = kb.KeysFor(c)
+		}
+
+		typed := true
+		for _, key := range keys {
+			if _, ok := lookup(key); !ok {
+				typed = false
+			}
+		}
+		if !typed {
+			// n-grams are not counted across characters that can't be typed
+			recent = recent[:0]
+			prev = 0
+			continue
+		}
+
+		for _, key := range keys {
+			t, _ := lookup(key)
+			from := positions[t.finger]
+			dist := math.Hypot(t.pos.X-from.X, t.pos.Y-from.Y)
+
+			sim.Keys++
+			sim.Travel += dist
+			sim.FingerTravel[t.finger] += dist
+			sim.FingerPresses[t.finger]++
+			positions[t.finger] = t.pos
+
+			for d := 1; d <= len(recent); d++ {
+				before := recent[len(recent)-d]
+				if before.finger == t.finger && before.key != key {
+					sim.SameFinger[d]++
+				}
+			}
+
+			if len(recent) == MaxSameFingerDistance {
+				copy(recent, recent[1:])
+				recent = recent[:MaxSameFingerDistance-1]
+			}
+			recent = append(recent, press{key, t.finger})
+		}
+		prev = c
+	}
+
+	return sim
+}
+
+// A text file for Simulate, read into memory once so it can be typed on every keyboard of an annealing
+type Text struct {
+	Path  string
+	runes []rune
+}
+
+// Reads a text file for Simulate
+func LoadText(path string) (*Text, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Text{path, []rune(string(data))}, nil
+}
+
+// Types the text on a keyboard with Simulate
+func (t *Text) Simulate(kb *kbd.Keyboard) *Simulation {
+	return Simulate(kb, t.runes)
+}
+
+// Creates a metric that simulates typing text and scores the total travel distance in hundredths of
+// a key width, lower is better. The CharFreq passed to the metric is not used.
+func TravelMetric(text *Text) Metric {
+	return func(kb *kbd.Keyboard, cf *kbd.CharFreq) int {
+		return int(math.Round(text.Simulate(kb).Travel * 100))
+	}
+}
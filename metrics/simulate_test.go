@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	kbd "kbannealing/keyboard"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func simulate(t *testing.T, kb *kbd.Keyboard, text string) *Simulation {
+	t.Helper()
+	return Simulate(kb, []rune(text))
+}
+
+func TestSimulate(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+
+	// home row keys don't move the fingers
+	sim := simulate(t, kb, "asdf jkl;")
+	if sim.Keys != 8 || sim.Travel != 0 {
+		t.Errorf("Simulate() = %d keys, %f travel but want 8 keys, 0 travel", sim.Keys, sim.Travel)
+	}
+
+	// f -> r -> f moves the left index up and back down
+	sim = simulate(t, kb, "frf")
+	expected := 2 * math.Hypot(0.25, 1)
+	if math.Abs(sim.Travel-expected) > 1e-9 || math.Abs(sim.FingerTravel[3]-expected) > 1e-9 {
+		t.Errorf("Simulate() travel = %f but want %f", sim.Travel, expected)
+	}
+	if sim.SameFinger[1] != 2 || sim.SameFinger[2] != 0 || sim.FingerPresses[3] != 3 {
+		t.Errorf("Simulate() same finger = %v, presses = %v", sim.SameFinger, sim.FingerPresses)
+	}
+
+	// "fir" uses the left index on keys two apart, "fkor" on keys three apart
+	sim = simulate(t, kb, "fir\nfkor")
+	if sim.SameFinger[1] != 0 || sim.SameFinger[2] != 1 || sim.SameFinger[3] != 1 {
+		t.Errorf("Simulate() same finger = %v but want [0 0 1 1]", sim.SameFinger)
+	}
+}
+
+func TestSimulateLayers(t *testing.T) {
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithLayers(kbd.ShiftLayer()).WithThumbs(string(kbd.Blank) + " ")
+
+	// "A" is typed with the right shift, space with the right thumb
+	sim := simulate(t, kb, "A a")
+	if sim.Keys != 4 || sim.FingerPresses[7] != 1 || sim.FingerPresses[kbd.RightThumb] != 1 {
+		t.Errorf("Simulate() = %d keys, presses %v", sim.Keys, sim.FingerPresses)
+	}
+
+	path := filepath.Join(t.TempDir(), "text.txt")
+	if err := os.WriteFile(path, []byte("A a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	text, err := LoadText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TravelMetric(text)(kb, nil) != int(math.Round(sim.Travel*100)) {
+		t.Error("TravelMetric() does not match Simulate()")
+	}
+
+	if _, err := LoadText(filepath.Dir(path)); err == nil {
+		t.Error("LoadText() of a directory should fail")
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"kbannealing/corpus"
+	kbd "kbannealing/keyboard"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultFolder = "CharFreqData/mt-quotes"
+
+// Flags choosing and normalizing the frequency data, shared by the subcommands
+type corpusOptions struct {
+	text      *string
+	folder    *string
+	prose     *string
+	blend     *string
+	code      *string
+	codeExt   *string
+	lang      *string
+	quadgrams *bool
+}
+
+func addCorpusFlags(fs *flag.FlagSet) *corpusOptions {
+	return &corpusOptions{
+		text:      fs.String("text", "", "Use a wordlist txt file for data"),
+		folder:    fs.String("folder", defaultFolder, "Use a folder for data, containing monograms, bigrams, and trigrams.txt"),
+		prose:     fs.String("prose", "", "Use comma separated prose text files (optionally .gz) for data, counting n-grams across words"),
+		blend:     fs.String("blend", "", "Blend comma separated folders or word list txt files with weights for data, ex. CharFreqData/mt-quotes=2,code.txt=1"),
+		code:      fs.String("code", "", "Use a directory of source code for data, shifted symbols are counted on their base keys"),
+		codeExt:   fs.String("codeext", "", "Comma separated file extensions scanned with -code, ex. .go,.py"),
+		lang:      fs.String("lang", "", "Normalize the -text, -prose or -blend corpus for a language (en, de, nordic, ru) before counting n-grams"),
+		quadgrams: fs.Bool("quadgrams", false, "Extract quadgrams from -text, -prose or -code data, for the quadgram metrics"),
+	}
+}
+
+// Loads the frequency data from the one source given
+func (o *corpusOptions) load() (*kbd.CharFreq, error) {
+	sourceCount := 0
+	for _, source := range []string{*o.text, *o.prose, *o.blend, *o.code} {
+		if source != "" {
+			sourceCount++
+		}
+	}
+	if *o.folder != defaultFolder {
+		sourceCount++
+	}
+	if sourceCount > 1 {
+		return nil, fmt.Errorf("only one of -text, -folder, -prose, -blend and -code can be used for frequency data")
+	}
+	// folders are counted already and code is not normalized
+	if *o.lang != "" && *o.text == "" && *o.prose == "" && *o.blend == "" {
+		return nil, fmt.Errorf("-lang can only be used with -text, -prose or -blend")
+	}
+	// folders, and so blends, are read with their quadgrams.txt if they have one
+	if *o.quadgrams && *o.text == "" && *o.prose == "" && *o.code == "" {
+		return nil, fmt.Errorf("-quadgrams can only be used with -text, -prose or -code")
+	}
+
+	var cf *kbd.CharFreq
+	var err error
+	norm := corpus.Normalization{}
+	if *o.lang != "" {
+		norm, err = corpus.LanguageNormalization(*o.lang)
+		if err != nil {
+			return nil, fmt.Errorf("could not load frequency data due to error: %w", err)
+		}
+	}
+
+	if *o.code != "" {
+		var extensions []string
+		if *o.codeExt != "" {
+			extensions = strings.Split(*o.codeExt, ",")
+		}
+		opts := corpus.CodeScanOptions
+		opts.Quadgrams = *o.quadgrams
+		cf, err = corpus.FromCode(*o.code, extensions, opts)
+	} else if *o.blend != "" {
+		var sources []corpus.Source
+		sources, err = corpus.ParseSources(*o.blend)
+		if err == nil {
+			cf, err = corpus.LoadBlend(sources, norm)
+		}
+	} else if *o.prose != "" {
+		cf, err = corpus.FromFiles(strings.Split(*o.prose, ","),
+			corpus.ScanOptions{Normalization: norm, Space: true, Quadgrams: *o.quadgrams})
+	} else if *o.text != "" {
+		// every line is a text of its own, and lines are not limited in length
+		cf, err = corpus.FromFiles([]string{*o.text},
+			corpus.ScanOptions{Normalization: norm, Space: true, LineBreaks: true, Quadgrams: *o.quadgrams})
+	} else {
+		cf, err = kbd.CharFreqFromFolder(*o.folder)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not load frequency data due to error: %w", err)
+	}
+	return cf, nil
+}
+
+// Name of the flag the frequency data is loaded from, ex. "folder"
+func (o *corpusOptions) source() string {
+	switch {
+	case *o.code != "":
+		return "code"
+	case *o.blend != "":
+		return "blend"
+	case *o.prose != "":
+		return "prose"
+	case *o.text != "":
+		return "text"
+	}
+	return "folder"
+}
+
+// Describes the source of the frequency data, for the metadata of layouts
+func (o *corpusOptions) describe() string {
+	var desc string
+	switch o.source() {
+	case "code":
+		desc = "code " + *o.code
+		if *o.codeExt != "" {
+			desc += " (" + *o.codeExt + ")"
+		}
+	case "blend":
+		desc = "blend " + *o.blend
+	case "prose":
+		desc = "prose " + *o.prose
+	case "text":
+		desc = "text " + *o.text
+	default:
+		desc = "folder " + *o.folder
+	}
+
+	if *o.lang != "" {
+		desc += ", lang " + *o.lang
+	}
+	return desc
+}
+
+// Key activating the symbol layers, and the finger pressing it unless -symbolkey is given
+const (
+	symbolLayerKey   = '◆'
+	defaultSymbolKey = "LP"
+)
+
+// Flags adding layers, thumb keys and adaptive keys to every keyboard, shared by the subcommands
+type keyboardOptions struct {
+	layouts     *string
+	shift       *bool
+	symbols     *string
+	symbolKey   *string
+	space       *string
+	thumbLetter *bool
+	repeat      *string
+	magic       *string
+	magicRules  *string
+}
+
+func addKeyboardFlags(fs *flag.FlagSet) *keyboardOptions {
+	return &keyboardOptions{
+		layouts:     fs.String("layouts", "layouts.json", "JSON file of layouts in row form by name"),
+		shift:       fs.Bool("shift", false, "Add the shift layer, so capitals and shifted symbols are typed with a shift key"),
+		symbols:     fs.String("symbols", "", "Add a symbol layer of 31 characters in row form, use · for empty keys"),
+		symbolKey:   fs.String("symbolkey", defaultSymbolKey, "Finger pressing the symbol layer key, from LP (left pinky) to RP (right pinky)"),
+		space:       fs.String("space", "", "Put space on the left or right thumb key, so metrics include space"),
+		thumbLetter: fs.Bool("thumbletter", false, "Add a thumb key opposite of space that annealing may put a letter on"),
+		repeat:      fs.String("repeat", "", "Put a repeat key in place of a character of the layouts, or on the free thumb key with \"thumb\""),
+		magic:       fs.String("magic", "", "Put a magic key in place of a character of the layouts, or on the free thumb key with \"thumb\""),
+		magicRules:  fs.String("magicrules", "", "Comma separated magic key rules of the previous and typed character, ex. ea,ou"),
+	}
+}
+
+// Keyboards are built from a template holding the layers, thumb keys and magic rules.
+// Adaptive keys replace a character of every layout, or take the free thumb key.
+type keyboardTemplate struct {
+	template *kbd.Keyboard
+	replaced map[rune]rune
+}
+
+func (o *keyboardOptions) build() (*keyboardTemplate, error) {
+	layers := []kbd.Layer{}
+	if *o.shift {
+		layers = append(layers, kbd.ShiftLayer())
+	}
+	if *o.symbols != "" {
+		finger := kbd.FingerIndex(*o.symbolKey)
+		if finger == -1 {
+			return nil, fmt.Errorf("invalid symbol layer finger %s, use one of %s", *o.symbolKey, strings.Join(kbd.FingerNames, ", "))
+		}
+		if utf8.RuneCountInString(*o.symbols) != 31 {
+			return nil, fmt.Errorf("symbol layer must have 31 characters, got %d", utf8.RuneCountInString(*o.symbols))
+		}
+		layers = append(layers, kbd.SymbolLayer("symbols", *o.symbols, symbolLayerKey, finger))
+	}
+
+	thumbs := ""
+	switch *o.space {
+	case "":
+	case "left":
+		thumbs = " " + string(kbd.Blank)
+	case "right":
+		thumbs = string(kbd.Blank) + " "
+	default:
+		return nil, fmt.Errorf("invalid space thumb %s, use left or right", *o.space)
+	}
+	if *o.thumbLetter && thumbs == "" {
+		return nil, fmt.Errorf("cannot use -thumbletter without -space")
+	}
+
+	replaced := map[rune]rune{}
+	for _, adaptive := range []struct {
+		flag string
+		key  rune
+	}{{*o.repeat, kbd.RepeatKey}, {*o.magic, kbd.MagicKey}} {
+		if adaptive.flag == "" {
+			continue
+		}
+		if adaptive.flag == "thumb" {
+			if !strings.ContainsRune(thumbs, kbd.Blank) || *o.thumbLetter {
+				return nil, fmt.Errorf("adaptive keys on a thumb need -space and a free thumb key, without -thumbletter")
+			}
+			thumbs = strings.Replace(thumbs, string(kbd.Blank), string(adaptive.key), 1)
+			continue
+		}
+		if utf8.RuneCountInString(adaptive.flag) != 1 {
+			return nil, fmt.Errorf("invalid adaptive key position %s, use a character or thumb", adaptive.flag)
+		}
+		old, _ := utf8.DecodeRuneInString(adaptive.flag)
+		replaced[old] = adaptive.key
+	}
+
+	magicRules, err := kbd.ParseMagicRules(*o.magicRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid magic rules: %w", err)
+	}
+
+	t := &keyboardTemplate{
+		template: kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithThumbs(thumbs).WithLayers(layers...).WithMagic(magicRules),
+		replaced: replaced,
+	}
+	start, err := t.keyboard(LayoutEntry{Layout: t.template.Layout})
+	if err != nil {
+		return nil, err
+	}
+	t.template = start
+	return t, nil
+}
+
+// Creates a keyboard of the template for a layout of a layouts file, placing the adaptive keys on it.
+// The thumb keys, layer keys and magic rules saved with the layout replace those of the template,
+// layers the template doesn't have are added as symbol layers on the default symbol key.
+func (t *keyboardTemplate) keyboard(entry LayoutEntry) (*kbd.Keyboard, error) {
+	layout := entry.Layout
+	var err error
+	for old, key := range t.replaced {
+		// annealing may have moved the key to a thumb
+		if strings.ContainsRune(entry.Thumbs, key) {
+			continue
+		}
+		layout, err = kbd.PlaceKey(layout, old, key)
+		if err != nil {
+			return nil, fmt.Errorf("could not place adaptive keys: %w", err)
+		}
+	}
+	kb := t.template.WithLayout(layout)
+
+	if entry.Thumbs != "" {
+		if utf8.RuneCountInString(entry.Thumbs) > 2 {
+			return nil, fmt.Errorf("thumb keys %q are more than the 2 thumb keys", entry.Thumbs)
+		}
+		kb = kb.WithThumbs(entry.Thumbs)
+	}
+
+	if len(entry.Layers) > 0 {
+		layers := slices.Clone(kb.Layers)
+		for _, name := range SortedKeys(entry.Layers) {
+			keys := entry.Layers[name]
+			if utf8.RuneCountInString(keys) != 31 {
+				return nil, fmt.Errorf("layer %s must have 31 characters, got %d", name, utf8.RuneCountInString(keys))
+			}
+			idx := slices.IndexFunc(layers, func(l kbd.Layer) bool { return l.Name == name && !l.Shifted })
+			if idx == -1 {
+				layers = append(layers, kbd.SymbolLayer(name, keys, symbolLayerKey, kbd.FingerIndex(defaultSymbolKey)))
+			} else {
+				layers[idx].Keys = keys
+			}
+		}
+		kb = kb.WithLayers(layers...)
+	}
+
+	if entry.Magic != "" {
+		rules, err := kbd.ParseMagicRules(entry.Magic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid magic rules: %w", err)
+		}
+		kb = kb.WithMagic(rules)
+	}
+	return kb, nil
+}
+
+// Creates keyboards of the template for all layouts of a layouts file
+func (t *keyboardTemplate) loadKeyboards(path string) (map[string]*kbd.Keyboard, error) {
+	layouts, err := loadLayoutFromJSON(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load layout JSON due to error: %w", err)
+	}
+
+	if err := validateLayouts(layouts, ""); err != nil {
+		return nil, err
+	}
+
+	keyboards := map[string]*kbd.Keyboard{}
+	for name, entry := range layouts {
+		keyboards[name], err = t.keyboard(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return keyboards, nil
+}
+
+// Validates every layout of a layouts file, returning the problems of all invalid layouts at once.
+// Letters of alphabet are checked as well if it is not empty.
+func validateLayouts(layouts LayoutMap, alphabet string) error {
+	errs := []error{}
+	for _, name := range SortedKeys(layouts) {
+		entry := layouts[name]
+		if err := kbd.Validate(name, entry.Layout, entry.Thumbs, entry.Layers, entry.Geometry, entry.FingerMap, entry.offLayout(alphabet)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
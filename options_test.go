@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	kbd "kbannealing/keyboard"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTextLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	// a line longer than the 64KB a bufio.Scanner reads by default
+	text := "the\n" + strings.Repeat("and ", 30000) + "\n"
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addCorpusFlags(fs)
+	if err := fs.Parse([]string{"-text", path}); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := opts.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Chars['a'] != 30000 || cf.Trigrams["and"] != 30000 || cf.Trigrams["the"] != 1 || cf.Bigrams["ea"] != 0 {
+		t.Errorf("load() of -text = %d a, %d and, %d the, %d ea", cf.Chars['a'], cf.Trigrams["and"], cf.Trigrams["the"], cf.Bigrams["ea"])
+	}
+}
+
+func buildTemplate(t *testing.T, args ...string) *keyboardTemplate {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := addKeyboardFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	template, err := opts.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return template
+}
+
+func TestLoadKeyboards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layouts.json")
+	symbols := "!@#$%^&*()[]{}<>-_=+\\|`~·····:\""
+	layouts := LayoutMap{
+		"qwerty": {Layout: "qwertyuiopasdfghjkl;'zxcvbnm,./"},
+		"full": {
+			Layout: "qwfpgjluy;ar·tdhneio'zxcvbkm,./",
+			Thumbs: "s ",
+			Layers: map[string]string{"symbols": symbols},
+			Magic:  "ea,ou",
+		},
+	}
+	if err := saveLayoutToJSON(path, layouts); err != nil {
+		t.Fatal(err)
+	}
+
+	keyboards, err := buildTemplate(t, "-space", "right", "-magicrules", "th").loadKeyboards(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// layouts without keys of their own use the template
+	qwerty := keyboards["qwerty"]
+	if qwerty.Thumbs != "· " || qwerty.Magic.String() != "th" || len(qwerty.Layers) != 0 {
+		t.Errorf("qwerty = thumbs %q, magic %s, %d layers but want the template", qwerty.Thumbs, qwerty.Magic, len(qwerty.Layers))
+	}
+
+	full := keyboards["full"]
+	if full.Thumbs != "s " || full.Magic.String() != "ea,ou" {
+		t.Errorf("full = thumbs %q, magic %s but want the saved ones", full.Thumbs, full.Magic)
+	}
+	if len(full.Layers) != 1 || full.Layers[0].Keys != symbols || full.Layers[0].Activators[0].Key != symbolLayerKey {
+		t.Errorf("full layers = %v but want the saved symbol layer", full.Layers)
+	}
+	if full.GroupId['s'] != kbd.LeftThumb {
+		t.Errorf("full types s with group %d but want the left thumb", full.GroupId['s'])
+	}
+
+	// saved layer keys replace the keys of the template's layer of the same name
+	other := "¡²³¤€¼½¾‘’¥×÷«»–—±¶§©®™°·····¿…"
+	keyboards, err = buildTemplate(t, "-symbols", other, "-symbolkey", "RP").loadKeyboards(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := keyboards["full"].Layers
+	if len(layers) != 1 || layers[0].Keys != symbols || layers[0].Activators[0].Finger != 7 {
+		t.Errorf("full layers = %v but want the saved keys on the RP symbol key", layers)
+	}
+	if keyboards["qwerty"].Layers[0].Keys != other {
+		t.Errorf("qwerty layers = %v but want the template's", keyboards["qwerty"].Layers)
+	}
+
+	for name, entry := range map[string]LayoutEntry{
+		"thumbs": {Layout: "qwertyuiopasdfghjkl;'zxcvbnm,./", Thumbs: "abc"},
+		"layers": {Layout: "qwertyuiopasdfghjkl;'zxcvbnm,./", Layers: map[string]string{"symbols": "!@#"}},
+		"magic":  {Layout: "qwertyuiopasdfghjkl;'zxcvbnm,./", Magic: "eao"},
+	} {
+		if _, err := buildTemplate(t).keyboard(entry); err == nil {
+			t.Errorf("keyboard() with invalid %s should fail", name)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Chart sizes in pixels and colors
+const (
+	chartWidth    = 1600
+	chartHeight   = 800
+	chartTop      = 60
+	chartBottom   = 200
+	chartLeft     = 90
+	chartLegend   = 220
+	chartFontSize = 13
+)
+
+var (
+	chartBackground = color.RGBA{0x2e, 0x34, 0x40, 255}
+	chartText       = color.RGBA{0xd8, 0xde, 0xe9, 255}
+)
+
+// Anchors of the viridis color map, a palette suitable for colorblind readers
+var viridis = []color.RGBA{
+	{0x44, 0x01, 0x54, 255},
+	{0x3b, 0x52, 0x8b, 255},
+	{0x21, 0x91, 0x8c, 255},
+	{0x5e, 0xc9, 0x62, 255},
+	{0xfd, 0xe7, 0x25, 255},
+}
+
+// Returns n colors evenly spaced over the viridis color map
+func palette(n int) []color.RGBA {
+	colors := make([]color.RGBA, n)
+	for i := range colors {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1)
+		}
+
+		pos := t * float64(len(viridis)-1)
+		lo := min(int(pos), len(viridis)-2)
+		frac := pos - float64(lo)
+		a, b := viridis[lo], viridis[lo+1]
+		mix := func(x, y uint8) uint8 {
+			return uint8(math.Round(float64(x) + (float64(y)-float64(x))*frac))
+		}
+		colors[i] = color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+	}
+	return colors
+}
+
+// Layout names in order with the "000 " prefix of optimized layouts removed, and the sorted stat names
+func chartAxes(stats map[string]map[string]float64) ([]string, []string, []string) {
+	layouts := make([]string, 0, len(stats))
+	statSet := map[string]bool{}
+	for name, values := range stats {
+		layouts = append(layouts, name)
+		for stat := range values {
+			statSet[stat] = true
+		}
+	}
+	slices.Sort(layouts)
+
+	names := make([]string, len(layouts))
+	for i, name := range layouts {
+		names[i] = strings.TrimPrefix(name, "000 ")
+	}
+
+	statNames := make([]string, 0, len(statSet))
+	for stat := range statSet {
+		statNames = append(statNames, stat)
+	}
+	slices.Sort(statNames)
+
+	return layouts, names, statNames
+}
+
+// Largest value of a stat over all layouts
+func statMax(stats map[string]map[string]float64, stat string) float64 {
+	most := 0.0
+	for _, values := range stats {
+		most = max(most, values[stat])
+	}
+	return most
+}
+
+// Rounds a tick step up to 1, 2 or 5 times a power of ten
+func niceStep(step float64) float64 {
+	if step <= 0 {
+		return 1
+	}
+	pow := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5} {
+		if step <= m*pow {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+func chartStart(out *bufio.Writer, title string) {
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hex(chartBackground))
+	fmt.Fprintf(out, `<g font-family="Arial, Helvetica, sans-serif" font-size="%d" fill="%s">`+"\n", chartFontSize, hex(chartText))
+	fmt.Fprintf(out, `<text x="%d" y="%d" font-size="18" text-anchor="middle">%s</text>`+"\n",
+		(chartWidth-chartLegend)/2, chartTop/2, html.EscapeString(title))
+}
+
+// Draws a legend of colored squares to the right of the chart
+func chartLegendBox(out *bufio.Writer, labels []string, colors []color.RGBA) {
+	x := chartWidth - chartLegend + 30
+	y := (chartHeight - len(labels)*22) / 2
+	for i, label := range labels {
+		fmt.Fprintf(out, `<rect x="%d" y="%d" width="14" height="14" fill="%s"/>`+"\n", x, y+i*22, hex(colors[i]))
+		fmt.Fprintf(out, `<text x="%d" y="%d" dominant-baseline="central">%s</text>`+"\n",
+			x+22, y+i*22+7, html.EscapeString(label))
+	}
+}
+
+func chartEnd(out *bufio.Writer) error {
+	fmt.Fprintln(out, "</g>")
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+// Writes an SVG bar chart of stats by layout name and stat name, as computed by ProcessStats.
+// Bars are grouped by layout with one color per stat, and each bar is annotated with its
+// value relative to the largest value of its stat.
+func BarChart(w io.Writer, stats map[string]map[string]float64) error {
+	layouts, names, statNames := chartAxes(stats)
+	colors := palette(len(statNames))
+	out := bufio.NewWriter(w)
+	chartStart(out, "Metric Comparison")
+
+	plotW := float64(chartWidth - chartLeft - chartLegend)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	bottom := float64(chartHeight - chartBottom)
+
+	most := 0.0
+	for _, stat := range statNames {
+		most = max(most, statMax(stats, stat))
+	}
+	step := niceStep(most / 5)
+	top := math.Max(step, math.Ceil(most/step)*step)
+	y := func(val float64) float64 {
+		return bottom - val/top*plotH
+	}
+
+	// axes and ticks
+	fmt.Fprintf(out, `<rect x="%d" y="%d" width="%g" height="%g" fill="none" stroke="%s"/>`+"\n",
+		chartLeft, chartTop, plotW, plotH, hex(chartText))
+	for val := 0.0; val <= top+step/2; val += step {
+		fmt.Fprintf(out, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="%s"/>`+"\n",
+			chartLeft-5, y(val), chartLeft, y(val), hex(chartText))
+		fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="central">%g</text>`+"\n",
+			chartLeft-8, y(val), math.Round(val*1000)/1000)
+	}
+	fmt.Fprintf(out, `<text x="%d" y="%.1f" text-anchor="middle" transform="rotate(-90 %d %.1f)">Percentage</text>`+"\n",
+		chartLeft-60, bottom-plotH/2, chartLeft-60, bottom-plotH/2)
+	fmt.Fprintf(out, `<text x="%.1f" y="%d" text-anchor="middle">Layout</text>`+"\n",
+		chartLeft+plotW/2, chartHeight-15)
+
+	// one group of bars per layout, leaving a bar of space between groups
+	group := plotW / float64(max(len(layouts), 1))
+	bar := group / float64(len(statNames)+1)
+	for i, layout := range layouts {
+		x := float64(chartLeft) + float64(i)*group + bar/2
+
+		for j, stat := range statNames {
+			val := stats[layout][stat]
+			bx := x + float64(j)*bar
+			fmt.Fprintf(out, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s %s: %.2f</title></rect>`+"\n",
+				bx, y(val), bar, bottom-y(val), hex(colors[j]),
+				html.EscapeString(names[i]), html.EscapeString(stat), val)
+
+			if most := statMax(stats, stat); most > 0 {
+				share := strings.TrimPrefix(fmt.Sprintf("%.3f", val/most), "0")
+				fmt.Fprintf(out, `<text x="%.1f" y="%.1f" font-size="9" text-anchor="middle">x%s</text>`+"\n",
+					bx+bar/2, y(val)-3, share)
+			}
+		}
+
+		// layout names are rotated under the middle of their group
+		cx := x + bar*float64(len(statNames))/2
+		fmt.Fprintf(out, `<text x="%.1f" y="%.1f" text-anchor="end" transform="rotate(-45 %.1f %.1f)">%s</text>`+"\n",
+			cx, bottom+15, cx, bottom+15, html.EscapeString(names[i]))
+	}
+
+	chartLegendBox(out, statNames, colors)
+	return chartEnd(out)
+}
+
+// Writes an SVG radar chart of stats by layout name and stat name, as computed by ProcessStats.
+// Every stat is an axis scaled to its largest value, and every layout is a polygon.
+func RadarChart(w io.Writer, stats map[string]map[string]float64) error {
+	layouts, names, statNames := chartAxes(stats)
+	colors := palette(len(layouts))
+	out := bufio.NewWriter(w)
+	chartStart(out, "Metric Comparison")
+
+	cx := float64(chartWidth-chartLegend) / 2
+	cy := float64(chartHeight+chartTop) / 2
+	radius := float64(chartHeight-chartTop)/2 - 60
+	point := func(axis int, share float64) (float64, float64) {
+		angle := 2*math.Pi*float64(axis)/float64(len(statNames)) - math.Pi/2
+		return cx + math.Cos(angle)*radius*share, cy + math.Sin(angle)*radius*share
+	}
+	polygon := func(shares []float64) string {
+		points := make([]string, len(shares))
+		for i, share := range shares {
+			x, y := point(i, share)
+			points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+		}
+		return strings.Join(points, " ")
+	}
+
+	// rings at every fifth of the largest values, and one axis per stat
+	for ring := 1; ring <= 5; ring++ {
+		shares := make([]float64, len(statNames))
+		for i := range shares {
+			shares[i] = float64(ring) / 5
+		}
+		fmt.Fprintf(out, `<polygon points="%s" fill="none" stroke="%s" stroke-opacity="0.3"/>`+"\n",
+			polygon(shares), hex(chartText))
+	}
+	for i, stat := range statNames {
+		x, y := point(i, 1)
+		fmt.Fprintf(out, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-opacity="0.5"/>`+"\n",
+			cx, cy, x, y, hex(chartText))
+
+		lx, ly := point(i, 1.12)
+		fmt.Fprintf(out, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="central">%s (max %.2f)</text>`+"\n",
+			lx, ly, html.EscapeString(stat), statMax(stats, stat))
+	}
+
+	for i, layout := range layouts {
+		shares := make([]float64, len(statNames))
+		for j, stat := range statNames {
+			if most := statMax(stats, stat); most > 0 {
+				shares[j] = stats[layout][stat] / most
+			}
+		}
+		fmt.Fprintf(out, `<polygon points="%s" fill="%s" fill-opacity="0.15" stroke="%s" stroke-width="2"><title>%s</title></polygon>`+"\n",
+			polygon(shares), hex(colors[i]), hex(colors[i]), html.EscapeString(names[i]))
+	}
+
+	chartLegendBox(out, names, colors)
+	return chartEnd(out)
+}
+
+// Writes a bar chart, or a radar chart if radar is set, of stats to an SVG file
+func SaveChart(path string, stats map[string]map[string]float64, radar bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if radar {
+		err = RadarChart(f, stats)
+	} else {
+		err = BarChart(f, stats)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var chartStats = map[string]map[string]float64{
+	"000 optimized sfb": {"sfb": 1, "roll": 40},
+	"qwerty":            {"sfb": 4, "roll": 20},
+}
+
+func TestBarChart(t *testing.T) {
+	var buf bytes.Buffer
+	if err := BarChart(&buf, chartStats); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("BarChart() is not valid XML: %s", err)
+	}
+	// the 000 prefix is removed, and bars are relative to the largest value of their stat
+	for _, want := range []string{">optimized sfb<", ">qwerty<", ">x.250<", ">x.500<", ">x1.000<"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("BarChart() does not contain %s", want)
+		}
+	}
+	if n := strings.Count(svg, "<title>"); n != 4 {
+		t.Errorf("BarChart() has %d bars but want 4", n)
+	}
+}
+
+func TestRadarChart(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RadarChart(&buf, chartStats); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("RadarChart() is not valid XML: %s", err)
+	}
+	// 5 rings and one polygon per layout
+	if n := strings.Count(svg, "<polygon "); n != 7 {
+		t.Errorf("RadarChart() has %d polygons but want 7", n)
+	}
+	if !strings.Contains(svg, ">roll (max 40.00)<") {
+		t.Errorf("RadarChart() does not label the roll axis")
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	for step, want := range map[float64]float64{0.3: 0.5, 1: 1, 13: 20, 16.2: 20, 60: 100} {
+		if got := niceStep(step); got != want {
+			t.Errorf("niceStep(%g) = %g but want %g", step, got, want)
+		}
+	}
+}
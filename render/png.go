@@ -0,0 +1,75 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// Pixels per dot of the bitmap font
+const (
+	labelScale = labelSize / 7
+	titleScale = titleSize / 7
+)
+
+// Writes a PNG image of the layouts stacked vertically. Labels are drawn with a bitmap font
+// covering ASCII, use SVG for other characters.
+func PNG(w io.Writer, layouts []Layout, opts Options) error {
+	s := newScene(layouts, opts)
+	img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	for _, t := range s.titles {
+		drawText(img, t.text, t.x, t.y, titleScale)
+	}
+	for _, k := range s.keys {
+		fillRoundedRect(img, k.x, k.y, keySize, keySize, keyRadius, k.stroke)
+		b := k.border
+		fillRoundedRect(img, k.x+b, k.y+b, keySize-2*b, keySize-2*b, max(keyRadius-b, 0), k.fill)
+		drawText(img, k.label, k.x+keySize/2, k.y+keySize/2, labelScale)
+	}
+
+	return png.Encode(w, img)
+}
+
+func fillRoundedRect(img *image.RGBA, x, y, w, h, r int, c color.RGBA) {
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			// distance into the corner square, if the pixel is in one
+			dx := max(r-px, px-(w-1-r), 0)
+			dy := max(r-py, py-(h-1-r), 0)
+			if dx*dx+dy*dy > r*r {
+				continue
+			}
+			img.SetRGBA(x+px, y+py, c)
+		}
+	}
+}
+
+// Draws text centered on (cx, cy)
+func drawText(img *image.RGBA, text string, cx, cy int, scale int) {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return
+	}
+
+	// glyphs are 5 dots wide with 1 dot between them
+	width := (len(runes)*6 - 1) * scale
+	x := cx - width/2
+	y := cy - 7*scale/2
+
+	for _, r := range runes {
+		for col, bits := range glyph(r) {
+			for row := 0; row < 7; row++ {
+				if bits&(1<<row) == 0 {
+					continue
+				}
+				dot := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale)
+				draw.Draw(img, dot, &image.Uniform{textColor}, image.Point{}, draw.Src)
+			}
+		}
+		x += 6 * scale
+	}
+}
@@ -0,0 +1,232 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	kbd "kbannealing/keyboard"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sizes in pixels of the keys, the margins and the text
+const (
+	keySize     = 50
+	keySpacing  = 10
+	keyRadius   = 10
+	margin      = 20
+	titleHeight = 40
+	labelSize   = 20
+	titleSize   = 24
+)
+
+// A named keyboard drawn in an image
+type Layout struct {
+	Name     string
+	Keyboard *kbd.Keyboard
+}
+
+type Options struct {
+	// Colors keys from white to red by the frequency of their character in Heatmap.Chars, if set
+	Heatmap *kbd.CharFreq
+	// Colors keys by the finger pressing them. With a heatmap, the outline of keys is colored instead.
+	Fingers bool
+}
+
+var (
+	background = color.RGBA{255, 255, 255, 255}
+	keyFill    = color.RGBA{211, 211, 211, 255}
+	outline    = color.RGBA{0, 0, 0, 255}
+	textColor  = color.RGBA{0, 0, 0, 255}
+)
+
+// Colors of the fingers, in the same order as kb.Groups
+var fingerColors = []color.RGBA{
+	{237, 125, 125, 255},
+	{240, 176, 106, 255},
+	{235, 222, 110, 255},
+	{140, 214, 127, 255},
+	{115, 204, 196, 255},
+	{122, 165, 230, 255},
+	{166, 138, 222, 255},
+	{222, 138, 200, 255},
+	{190, 190, 190, 255},
+	{160, 160, 160, 255},
+}
+
+type key struct {
+	x, y   int
+	label  string
+	fill   color.RGBA
+	stroke color.RGBA
+	// outline width in pixels
+	border int
+}
+
+type title struct {
+	// center of the title
+	x, y int
+	text string
+}
+
+// Everything drawn in an image, shared by the PNG and SVG output
+type scene struct {
+	width, height int
+	keys          []key
+	titles        []title
+}
+
+// A block of keys drawn under one title
+type board struct {
+	title  string
+	keys   []rune
+	points []kbd.Point
+	// finger pressing each key, -1 if unknown
+	fingers []int
+}
+
+// Splits a keyboard into its base layout with the thumb keys, followed by one board per layer
+// that is not shifted, as shifted layers follow the base layout.
+func boards(l Layout) []board {
+	kb := l.Keyboard
+	base := board{title: l.Name}
+	for i, r := range []rune(kb.Layout) {
+		base.add(r, kbd.KeyPosition(i), fingerOf(kb, r))
+	}
+	for i, r := range []rune(kb.Thumbs) {
+		base.add(r, kbd.ThumbPosition(i), kbd.LeftThumb+i)
+	}
+
+	out := []board{base}
+	layout := []rune(kb.Layout)
+	for _, layer := range kb.Layers {
+		if layer.Shifted {
+			continue
+		}
+
+		b := board{title: fmt.Sprintf("%s [%s]", l.Name, layer.Name)}
+		for i, r := range []rune(layer.Keys) {
+			b.add(r, kbd.KeyPosition(i), fingerOf(kb, layout[i]))
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (b *board) add(r rune, p kbd.Point, finger int) {
+	b.keys = append(b.keys, r)
+	b.points = append(b.points, p)
+	b.fingers = append(b.fingers, finger)
+}
+
+func fingerOf(kb *kbd.Keyboard, r rune) int {
+	if finger, ok := kb.GroupId[r]; ok {
+		return finger
+	}
+	return -1
+}
+
+func label(r rune) string {
+	switch r {
+	case ' ':
+		return "␣"
+	case kbd.Blank:
+		return ""
+	}
+	return string(r)
+}
+
+// Interpolates from white to red by the share of the most frequent character
+func heatColor(val int, most int) color.RGBA {
+	if most == 0 {
+		return background
+	}
+	t := float64(val) / float64(most)
+	return color.RGBA{
+		255,
+		uint8(255 - t*(255-70)),
+		uint8(255 - t*(255-60)),
+		255,
+	}
+}
+
+// Lays out the boards of all layouts stacked vertically
+func newScene(layouts []Layout, opts Options) *scene {
+	all := []board{}
+	for _, l := range layouts {
+		all = append(all, boards(l)...)
+	}
+
+	most := 0
+	if opts.Heatmap != nil {
+		for _, b := range all {
+			for _, r := range b.keys {
+				most = max(most, opts.Heatmap.Chars[r])
+			}
+		}
+	}
+
+	unit := keySize + keySpacing
+	s := &scene{}
+	top := 0
+	for _, b := range all {
+		maxX, maxY := 0.0, 0.0
+		for _, p := range b.points {
+			maxX = max(maxX, p.X)
+			maxY = max(maxY, p.Y)
+		}
+		width := int(maxX*float64(unit)) + keySize + 2*margin
+		height := int(maxY*float64(unit)) + keySize + 2*margin + titleHeight
+		s.width = max(s.width, width)
+
+		s.titles = append(s.titles, title{width / 2, top + margin/2 + titleSize/2, b.title})
+		for i, r := range b.keys {
+			k := key{
+				x:      margin + int(b.points[i].X*float64(unit)),
+				y:      top + margin + titleHeight + int(b.points[i].Y*float64(unit)),
+				label:  label(r),
+				fill:   keyFill,
+				stroke: outline,
+				border: 1,
+			}
+
+			finger := b.fingers[i]
+			hasFinger := opts.Fingers && finger >= 0 && finger < len(fingerColors)
+			switch {
+			case opts.Heatmap != nil && hasFinger:
+				k.fill = heatColor(opts.Heatmap.Chars[r], most)
+				k.stroke = fingerColors[finger]
+				k.border = 4
+			case opts.Heatmap != nil:
+				k.fill = heatColor(opts.Heatmap.Chars[r], most)
+			case hasFinger:
+				k.fill = fingerColors[finger]
+			}
+			s.keys = append(s.keys, k)
+		}
+
+		top += height
+	}
+	s.height = top
+
+	return s
+}
+
+// Writes an image of the layouts to a file, as SVG if the path ends with .svg and PNG otherwise
+func SaveFile(path string, layouts []Layout, opts Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".svg") {
+		err = SVG(f, layouts, opts)
+	} else {
+		err = PNG(f, layouts, opts)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
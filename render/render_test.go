@@ -0,0 +1,66 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	kbd "kbannealing/keyboard"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	qwerty := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./")
+	layouts := []Layout{{"qwerty", qwerty}, {"thumbs", qwerty.WithThumbs(" ·")}}
+
+	var buf bytes.Buffer
+	if err := PNG(&buf, layouts, Options{Fingers: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 11 keys on the home row, 3 rows and a row of thumb keys
+	wantWidth := 10*60 + 15 + 50 + 2*margin
+	wantHeight := (2*60 + 50 + 2*margin + titleHeight) + (3*60 + 50 + 2*margin + titleHeight)
+	if b := img.Bounds(); b.Dx() != wantWidth || b.Dy() != wantHeight {
+		t.Errorf("PNG() size = %dx%d but want %dx%d", b.Dx(), b.Dy(), wantWidth, wantHeight)
+	}
+
+	// the corner of q is outside of its rounded key, its center is colored by the left pinky
+	x, y := margin, margin+titleHeight
+	if r, g, b, _ := img.At(x, y).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Errorf("PNG() corner of q = %d,%d,%d but want white", r>>8, g>>8, b>>8)
+	}
+	want := fingerColors[0]
+	if r, g, b, _ := img.At(x+3, y+keySize/2).RGBA(); uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+		t.Errorf("PNG() q = %d,%d,%d but want %v", r>>8, g>>8, b>>8, want)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	symbols := "!@#$%^&*()···········-=+_{}<>[]"
+	kb := kbd.NewKeyboard("qwertyuiopasdfghjkl;'zxcvbnm,./").WithLayers(kbd.SymbolLayer("symbols", symbols, '◆', 0))
+	cf := &kbd.CharFreq{Chars: map[rune]int{'e': 10, 'q': 0}}
+
+	var buf bytes.Buffer
+	if err := SVG(&buf, []Layout{{"sym", kb}}, Options{Heatmap: cf}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+
+	for _, want := range []string{">sym<", ">sym [symbols]<", ">&lt;<", ">&amp;<", "fill=\"#ff463c\""} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG() does not contain %s", want)
+		}
+	}
+	// 31 base keys and 31 symbol layer keys, blank keys have no label
+	if n := strings.Count(svg, "<rect x="); n != 62 {
+		t.Errorf("SVG() has %d keys but want 62", n)
+	}
+	if n := strings.Count(svg, "<text "); n != 2+31+20 {
+		t.Errorf("SVG() has %d texts but want %d", n, 2+31+20)
+	}
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+)
+
+// Writes an SVG image of the layouts stacked vertically
+func SVG(w io.Writer, layouts []Layout, opts Options) error {
+	s := newScene(layouts, opts)
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		s.width, s.height, s.width, s.height)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hex(background))
+	fmt.Fprintln(out, `<g font-family="Arial, Helvetica, sans-serif" text-anchor="middle" dominant-baseline="central">`)
+
+	for _, t := range s.titles {
+		fmt.Fprintf(out, `<text x="%d" y="%d" font-size="%d" fill="%s">%s</text>`+"\n",
+			t.x, t.y, titleSize, hex(textColor), html.EscapeString(t.text))
+	}
+	for _, k := range s.keys {
+		// the stroke is centered on the edge, so the rect is inset by half of it
+		inset := float64(k.border) / 2
+		fmt.Fprintf(out, `<rect x="%g" y="%g" width="%g" height="%g" rx="%d" fill="%s" stroke="%s" stroke-width="%d"/>`+"\n",
+			float64(k.x)+inset, float64(k.y)+inset, keySize-2*inset, keySize-2*inset, keyRadius,
+			hex(k.fill), hex(k.stroke), k.border)
+		if k.label != "" {
+			fmt.Fprintf(out, `<text x="%d" y="%d" font-size="%d" fill="%s">%s</text>`+"\n",
+				k.x+keySize/2, k.y+keySize/2, labelSize, hex(textColor), html.EscapeString(k.label))
+		}
+	}
+
+	fmt.Fprintln(out, "</g>")
+	fmt.Fprintln(out, "</svg>")
+	return out.Flush()
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}